@@ -14,12 +14,21 @@ import (
 	"github.com/chrlesur/orchestrator/internal/job"
 	"github.com/chrlesur/orchestrator/internal/pipeline"
 	"github.com/chrlesur/orchestrator/internal/plugin"
+	"github.com/chrlesur/orchestrator/internal/retention"
+	"github.com/chrlesur/orchestrator/internal/sandbox"
 	"github.com/chrlesur/orchestrator/internal/ui"
 	"github.com/chrlesur/orchestrator/pkg/logger"
 	"github.com/chrlesur/orchestrator/pkg/version"
 )
 
 func main() {
+	// Lorsque l'orchestrateur est relancé pour isoler un job, il prépare le bac à sable et
+	// exécute la commande du job sans rendre la main
+	sandbox.Init()
+	// De même, il se relance pour placer un job sous ses limites de ressources avant
+	// d'exécuter sa commande
+	job.InitLimits()
+
 	// Afficher la version
 	fmt.Printf("Orchestrator version %s\n", version.GetVersion())
 
@@ -63,10 +72,31 @@ func main() {
 	}
 
 	// Créer le gestionnaire de jobs
-	jobManager := job.NewManager(5, store, cfg.Jobs.DefaultTimeout, cfg.Jobs.MaxRetries, pluginManager)
+	jobManager := job.NewManager(cfg.Workers.Jobs, store, job.Settings{
+		DefaultTimeout: cfg.Jobs.DefaultTimeout,
+		GracePeriod:    cfg.Jobs.GracePeriod,
+		MaxRetries:     cfg.Jobs.MaxRetries,
+		RetryPolicy:    cfg.Jobs.Retry.Policy(),
+		LogDir:         cfg.Jobs.LogDir,
+		WorkspaceDir:   cfg.Jobs.WorkspaceDir,
+		CgroupRoot:     cfg.Jobs.CgroupRoot,
+		MaxOutputSize:  cfg.Jobs.MaxOutputSize,
+		OutputDir:      cfg.Jobs.OutputDir,
+		ArtifactDir:    cfg.Jobs.ArtifactDir,
+		RunAsUsers:     cfg.Jobs.RunAs.Users,
+		RunAsGroups:    cfg.Jobs.RunAs.Groups,
+		SandboxPaths:   cfg.Jobs.Sandbox.Paths,
+		SSHCommand:     cfg.SSH.Command,
+		SSHHosts:       cfg.SSH.Inventory(),
+		RecoveryPolicy: cfg.Recovery.Jobs,
+	}, pluginManager)
 
 	// Créer le gestionnaire de pipelines
-	pipelineManager := pipeline.NewManager(3, store, pluginManager)
+	pipelineManager := pipeline.NewManager(cfg.Workers.Pipelines, store, jobManager, cfg.Recovery.Pipelines)
+
+	// Lancer le nettoyage périodique des jobs et pipelines terminés
+	janitor := retention.NewJanitor(cfg.Retention.Policy(cfg.Server.IdempotencyWindow), store, jobManager, pipelineManager)
+	janitor.Start()
 
 	// Créer et lancer l'interface TUI dans une goroutine
 	tui := ui.NewTUI(jobManager, pipelineManager, pluginManager)
@@ -77,7 +107,7 @@ func main() {
 	}()
 
 	// Créer et lancer le serveur API dans une goroutine
-	apiServer := api.NewServer(jobManager, pipelineManager, pluginManager)
+	apiServer := api.NewServer(jobManager, pipelineManager, pluginManager, store, cfg.Server.IdempotencyWindow)
 	go func() {
 		logger.Info(fmt.Sprintf("Démarrage du serveur API sur :%d", cfg.Server.Port))
 		if err := apiServer.Run(fmt.Sprintf(":%d", cfg.Server.Port)); err != nil {
@@ -91,6 +121,7 @@ func main() {
 	<-c
 
 	// Arrêter proprement les gestionnaires
+	janitor.Stop()
 	jobManager.Shutdown()
 	pipelineManager.Shutdown()
 
@@ -9,6 +9,7 @@ require (
 	github.com/gorilla/mux v1.8.1
 	github.com/mattn/go-runewidth v0.0.16 // indirect
 	github.com/rivo/tview v0.0.0-20240921122403-a64fc48d7654
+	golang.org/x/crypto v0.28.0
 	golang.org/x/term v0.25.0 // indirect
 	golang.org/x/text v0.19.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// artifactInfo décrit un artefact collecté pour un job
+type artifactInfo struct {
+	Path      string    `json:"path"`
+	SHA256    string    `json:"sha256"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+	URL       string    `json:"url"`
+}
+
+func (s *Server) handleGetJobArtifacts(w http.ResponseWriter, r *http.Request) {
+	artifacts, err := s.jobManager.GetArtifacts(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+
+	infos := make([]artifactInfo, 0, len(artifacts))
+	for _, a := range artifacts {
+		infos = append(infos, artifactInfo{
+			Path:      a.Path,
+			SHA256:    a.SHA256,
+			Size:      a.Size,
+			CreatedAt: a.CreatedAt,
+			URL:       "/artifacts/" + a.SHA256,
+		})
+	}
+	respondJSON(w, http.StatusOK, infos)
+}
+
+// handleGetArtifact télécharge le contenu d'un artefact désigné par son empreinte SHA-256
+func (s *Server) handleGetArtifact(w http.ResponseWriter, r *http.Request) {
+	file, artifact, err := s.jobManager.OpenArtifact(mux.Vars(r)["sha"])
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Artifact not found")
+		return
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Artifact not found")
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(artifact.Path)))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", artifact.Size))
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, f)
+}
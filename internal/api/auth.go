@@ -3,6 +3,7 @@ package api
 import (
     "net/http"
     "crypto/subtle"
+    "fmt"
 )
 
 var apiKeys = map[string]string{
@@ -33,4 +34,22 @@ func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 
         next.ServeHTTP(w, r)
     }
-}
\ No newline at end of file
+}
+// apiKeyName retourne le nom de la clé d'API présentée par une requête, vide si elle est inconnue
+func apiKeyName(r *http.Request) string {
+    key := r.Header.Get("X-API-Key")
+    for name, validKey := range apiKeys {
+        if subtle.ConstantTimeCompare([]byte(key), []byte(validKey)) == 1 {
+            return name
+        }
+    }
+    return ""
+}
+
+// requester décrit l'origine d'une requête pour le journal d'audit
+func requester(r *http.Request) string {
+    if name := apiKeyName(r); name != "" {
+        return fmt.Sprintf("API key %s from %s", name, r.RemoteAddr)
+    }
+    return fmt.Sprintf("API client %s", r.RemoteAddr)
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+	"github.com/chrlesur/orchestrator/pkg/logger"
+)
+
+const (
+	idempotencyHeader = "Idempotency-Key"
+	replayedHeader    = "Idempotent-Replayed"
+
+	// Taille maximale d'un corps de requête conservé pour calculer son empreinte
+	maxIdempotentBodySize = 10 << 20
+)
+
+// idempotent rend une route de création rejouable : une requête répétée avec le même
+// Idempotency-Key pendant la fenêtre configurée retourne la ressource créée la première
+// fois, retrouvée avec lookup, au lieu d'en créer une nouvelle
+func (s *Server) idempotent(lookup func(id string) (interface{}, error), next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyHeader)
+		if key == "" {
+			next(w, r)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxIdempotentBodySize)
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			// MaxBytesReader s'arrête à la limite : un corps lu en entier jusqu'à elle est trop grand
+			if len(body) >= maxIdempotentBodySize {
+				respondError(w, http.StatusRequestEntityTooLarge, "Request payload too large")
+				return
+			}
+			respondError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(body)
+		hash := hex.EncodeToString(sum[:])
+
+		// Une clé est propre à une route et à une clé d'API ; les requêtes portant la même clé
+		// sont traitées une à une pour que deux envois simultanés ne créent pas deux ressources
+		storeKey := apiKeyName(r) + "#" + r.URL.Path + "#" + key
+		defer s.idempotencyLocks.lock(storeKey)()
+
+		rec, err := s.store.GetIdempotencyRecord(storeKey)
+		if err == nil && time.Since(rec.CreatedAt) < s.idempotencyWindow {
+			if rec.RequestHash != hash {
+				respondError(w, http.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request")
+				return
+			}
+			if resource, err := lookup(rec.ResourceID); err == nil {
+				logger.Info(fmt.Sprintf("Replaying request %s for idempotency key %s", r.URL.Path, key))
+				w.Header().Set(replayedHeader, "true")
+				respondJSON(w, http.StatusCreated, resource)
+				return
+			}
+		}
+
+		recorder := &creationRecorder{ResponseWriter: w}
+		next(recorder, r)
+		if recorder.status != http.StatusCreated {
+			return
+		}
+
+		var created struct{ ID string }
+		if err := json.Unmarshal(recorder.body.Bytes(), &created); err != nil || created.ID == "" {
+			return
+		}
+		err = s.store.SaveIdempotencyRecord(&models.IdempotencyRecord{
+			Key:         storeKey,
+			RequestHash: hash,
+			ResourceID:  created.ID,
+			CreatedAt:   time.Now(),
+		})
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to save idempotency key %s: %v", key, err))
+		}
+	}
+}
+
+// keyLocks sérialise les requêtes portant une même clé d'idempotence sans bloquer les autres
+type keyLocks struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock verrouille une clé et retourne la fonction qui la libère
+func (k *keyLocks) lock(key string) func() {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = make(map[string]*keyLock)
+	}
+	l, ok := k.locks[key]
+	if !ok {
+		l = &keyLock{}
+		k.locks[key] = l
+	}
+	l.refs++
+	k.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		k.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
+
+// creationRecorder conserve le statut et le corps d'une réponse tout en la transmettant
+type creationRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (c *creationRecorder) WriteHeader(status int) {
+	c.status = status
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *creationRecorder) Write(p []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.body.Write(p)
+	return c.ResponseWriter.Write(p)
+}
+
+func (s *Server) lookupJob(id string) (interface{}, error) {
+	return s.jobManager.GetJob(id)
+}
+
+func (s *Server) lookupPipeline(id string) (interface{}, error) {
+	return s.pipelineManager.GetPipeline(id)
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chrlesur/orchestrator/internal/db"
+	"github.com/chrlesur/orchestrator/pkg/logger"
+)
+
+// idempotencyTestServer retourne une route de création enveloppée par idempotent : chaque
+// appel du gestionnaire crée une ressource r1, r2... sauf pour le corps "fail"
+func idempotencyTestServer(t *testing.T, window time.Duration) http.HandlerFunc {
+	dir := t.TempDir()
+	if err := logger.Init("error", filepath.Join(dir, "orchestrator.log")); err != nil {
+		t.Fatal(err)
+	}
+	store, err := db.NewStore(filepath.Join(dir, "orchestrator.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(store.Close)
+	s := &Server{store: store, idempotencyWindow: window}
+
+	created := make(map[string]bool)
+	lookup := func(id string) (interface{}, error) {
+		if !created[id] {
+			return nil, fmt.Errorf("resource %s not found", id)
+		}
+		return map[string]string{"ID": id}, nil
+	}
+	return s.idempotent(lookup, func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Name string }
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "fail" {
+			respondError(w, http.StatusBadRequest, "rejected")
+			return
+		}
+		id := fmt.Sprintf("r%d", len(created)+1)
+		created[id] = true
+		respondJSON(w, http.StatusCreated, map[string]string{"ID": id})
+	})
+}
+
+func TestIdempotentReplay(t *testing.T) {
+	type request struct {
+		apiKey       string
+		key          string
+		body         string
+		wantStatus   int
+		wantID       string
+		wantReplayed bool
+	}
+	tests := []struct {
+		name     string
+		window   time.Duration
+		requests []request
+	}{
+		{"without key", time.Hour, []request{
+			{"admin_key", "", `{"Name": "a"}`, http.StatusCreated, "r1", false},
+			{"admin_key", "", `{"Name": "a"}`, http.StatusCreated, "r2", false},
+		}},
+		{"same key and body", time.Hour, []request{
+			{"admin_key", "k1", `{"Name": "a"}`, http.StatusCreated, "r1", false},
+			{"admin_key", "k1", `{"Name": "a"}`, http.StatusCreated, "r1", true},
+			{"admin_key", "k1", `{"Name": "a"}`, http.StatusCreated, "r1", true},
+		}},
+		{"same key, other body", time.Hour, []request{
+			{"admin_key", "k1", `{"Name": "a"}`, http.StatusCreated, "r1", false},
+			{"admin_key", "k1", `{"Name": "b"}`, http.StatusUnprocessableEntity, "", false},
+		}},
+		{"other key", time.Hour, []request{
+			{"admin_key", "k1", `{"Name": "a"}`, http.StatusCreated, "r1", false},
+			{"admin_key", "k2", `{"Name": "a"}`, http.StatusCreated, "r2", false},
+		}},
+		{"other API key", time.Hour, []request{
+			{"admin_key", "k1", `{"Name": "a"}`, http.StatusCreated, "r1", false},
+			{"user_key", "k1", `{"Name": "a"}`, http.StatusCreated, "r2", false},
+		}},
+		{"failed creation not recorded", time.Hour, []request{
+			{"admin_key", "k1", `{"Name": "fail"}`, http.StatusBadRequest, "", false},
+			{"admin_key", "k1", `{"Name": "a"}`, http.StatusCreated, "r1", false},
+		}},
+		{"expired key", time.Nanosecond, []request{
+			{"admin_key", "k1", `{"Name": "a"}`, http.StatusCreated, "r1", false},
+			{"admin_key", "k1", `{"Name": "b"}`, http.StatusCreated, "r2", false},
+		}},
+		{"body too large", time.Hour, []request{
+			{"admin_key", "k1", `{"Name": "` + strings.Repeat("a", maxIdempotentBodySize) + `"}`, http.StatusRequestEntityTooLarge, "", false},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := idempotencyTestServer(t, tt.window)
+			for i, req := range tt.requests {
+				r := httptest.NewRequest("POST", "/jobs", strings.NewReader(req.body))
+				r.Header.Set("X-API-Key", req.apiKey)
+				if req.key != "" {
+					r.Header.Set(idempotencyHeader, req.key)
+				}
+				w := httptest.NewRecorder()
+				handler(w, r)
+
+				if w.Code != req.wantStatus {
+					t.Fatalf("request %d: status = %d, want %d (%s)", i, w.Code, req.wantStatus, w.Body.String())
+				}
+				if replayed := w.Header().Get(replayedHeader) == "true"; replayed != req.wantReplayed {
+					t.Fatalf("request %d: replayed = %v, want %v", i, replayed, req.wantReplayed)
+				}
+				if req.wantID == "" {
+					continue
+				}
+				var resource struct{ ID string }
+				if err := json.Unmarshal(w.Body.Bytes(), &resource); err != nil || resource.ID != req.wantID {
+					t.Fatalf("request %d: resource = %s, want %s", i, w.Body.String(), req.wantID)
+				}
+			}
+		})
+	}
+}
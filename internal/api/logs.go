@@ -0,0 +1,150 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/chrlesur/orchestrator/internal/job"
+	"github.com/chrlesur/orchestrator/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// Intervalle de relecture du fichier de log en mode follow
+const logFollowInterval = 500 * time.Millisecond
+
+func (s *Server) handleGetJobLogs(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	jobID := vars["id"]
+	query := r.URL.Query()
+
+	stream := query.Get("stream")
+	if stream == "" {
+		stream = job.StreamStdout
+	}
+	attempt, err := queryInt(query.Get("attempt"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid attempt parameter")
+		return
+	}
+	tail, err := queryInt(query.Get("tail"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid tail parameter")
+		return
+	}
+	follow := query.Get("follow") == "true"
+
+	j, err := s.jobManager.GetJob(jobID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+	if attempt <= 0 {
+		attempt = j.Attempt
+	}
+
+	path, err := s.jobManager.LogPath(jobID, attempt, stream)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	content, offset, err := job.ReadTail(path, tail)
+	if err != nil {
+		if os.IsNotExist(err) {
+			respondError(w, http.StatusNotFound, "Log not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, content)
+
+	if follow {
+		s.followLog(w, r, j, attempt, path, offset)
+	}
+}
+
+// followLog envoie les nouvelles lignes du fichier de log tant que la tentative est en cours
+func (s *Server) followLog(w http.ResponseWriter, r *http.Request, j *models.Job, attempt int, path string, offset int64) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(logFollowInterval)
+	defer ticker.Stop()
+
+	for {
+		// L'état est lu avant la copie pour ne pas perdre les dernières lignes écrites
+		finished := j.Status != models.JobStatusRunning || j.Attempt != attempt
+
+		if n, _ := io.Copy(w, f); n > 0 {
+			flusher.Flush()
+		}
+		if finished {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// handleGetJobOutput télécharge la sortie complète d'un job, y compris lorsque
+// l'aperçu conservé sur le job a été tronqué
+func (s *Server) handleGetJobOutput(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	jobID := vars["id"]
+
+	j, err := s.jobManager.GetJob(jobID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+
+	if !j.OutputTruncated || j.OutputPath == "" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", jobID+".out"))
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, j.Result)
+		return
+	}
+
+	f, err := os.Open(j.OutputPath)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Output file not found")
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", jobID+".out"))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, f)
+}
+
+func queryInt(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// cloneRequest liste les champs remplacés lors de la copie d'un job ; les champs absents
+// sont repris de l'original et les variables d'env complètent celles de l'original
+type cloneRequest struct {
+	Name        *string             `json:"name"`
+	Command     *string             `json:"command"`
+	Args        *[]string           `json:"args"`
+	Env         map[string]string   `json:"env"`
+	WorkingDir  *string             `json:"working_dir"`
+	Stdin       *string             `json:"stdin"`
+	Priority    *priorityValue      `json:"priority"`
+	Timeout     string              `json:"timeout"`
+	GracePeriod string              `json:"grace_period"`
+	MaxRetries  *int                `json:"max_retries"`
+	RetryPolicy *retryPolicyRequest `json:"retry_policy"`
+	Locks       *[]lockRequest      `json:"locks"`
+	DependsOn   *[]string           `json:"depends_on"`
+}
+
+// apply remplace sur la copie les champs présents dans la requête
+func (req *cloneRequest) apply(j *models.Job) error {
+	if req.Name != nil {
+		j.Name = *req.Name
+	}
+	if req.Command != nil {
+		j.Command = *req.Command
+	}
+	if req.Args != nil {
+		j.Args = *req.Args
+	}
+	if len(req.Env) > 0 && j.Env == nil {
+		j.Env = make(map[string]string, len(req.Env))
+	}
+	for key, value := range req.Env {
+		j.Env[key] = value
+	}
+	if req.WorkingDir != nil {
+		j.WorkingDir = *req.WorkingDir
+	}
+	if req.Stdin != nil {
+		j.Stdin = *req.Stdin
+	}
+	if req.Priority != nil {
+		j.Priority = int(*req.Priority)
+	}
+	if req.Timeout != "" {
+		timeout, err := time.ParseDuration(req.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout: %v", err)
+		}
+		j.Timeout = timeout
+	}
+	if req.GracePeriod != "" {
+		grace, err := time.ParseDuration(req.GracePeriod)
+		if err != nil {
+			return fmt.Errorf("invalid grace period: %v", err)
+		}
+		j.GracePeriod = grace
+	}
+	if req.MaxRetries != nil {
+		j.MaxRetries = *req.MaxRetries
+	}
+	if req.RetryPolicy != nil {
+		policy, err := req.RetryPolicy.toModel()
+		if err != nil {
+			return err
+		}
+		j.RetryPolicy = policy
+	}
+	if req.Locks != nil {
+		j.Locks = toResourceLocks(*req.Locks)
+	}
+	if req.DependsOn != nil {
+		j.DependsOn = *req.DependsOn
+	}
+	return nil
+}
+
+// handleRerunJob soumet une nouvelle exécution d'un job avec sa définition d'origine
+func (s *Server) handleRerunJob(w http.ResponseWriter, r *http.Request) {
+	s.submitCopy(w, r, &cloneRequest{})
+}
+
+// handleCloneJob soumet une copie d'un job dont certains champs sont remplacés
+func (s *Server) handleCloneJob(w http.ResponseWriter, r *http.Request) {
+	var req cloneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	s.submitCopy(w, r, &req)
+}
+
+func (s *Server) submitCopy(w http.ResponseWriter, r *http.Request, req *cloneRequest) {
+	newJob, err := s.jobManager.CopyJob(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+	if err := req.apply(newJob); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := s.jobManager.SubmitJobFrom(newJob, requester(r)); err != nil {
+		respondSubmitError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, newJob)
+}
@@ -2,42 +2,79 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
+	"github.com/chrlesur/orchestrator/internal/db"
 	"github.com/chrlesur/orchestrator/internal/job"
+	"github.com/chrlesur/orchestrator/internal/lock"
 	"github.com/chrlesur/orchestrator/internal/models"
 	"github.com/chrlesur/orchestrator/internal/pipeline"
 	"github.com/chrlesur/orchestrator/internal/plugin"
+	"github.com/chrlesur/orchestrator/pkg/logger"
+	"github.com/chrlesur/orchestrator/pkg/utils"
 	"github.com/gorilla/mux"
 )
 
 type Server struct {
-	jobManager      *job.Manager
-	pipelineManager *pipeline.Manager
-	pluginManager   *plugin.PluginManager
-	router          *mux.Router
+	jobManager        *job.Manager
+	pipelineManager   *pipeline.Manager
+	pluginManager     *plugin.PluginManager
+	store             *db.Store
+	idempotencyWindow time.Duration
+	idempotencyLocks  keyLocks
+	router            *mux.Router
 }
 
-func NewServer(jobManager *job.Manager, pipelineManager *pipeline.Manager, pluginManager *plugin.PluginManager) *Server {
+func NewServer(jobManager *job.Manager, pipelineManager *pipeline.Manager, pluginManager *plugin.PluginManager, store *db.Store, idempotencyWindow time.Duration) *Server {
 	s := &Server{
-		jobManager:      jobManager,
-		pipelineManager: pipelineManager,
-		pluginManager:   pluginManager,
-		router:          mux.NewRouter(),
+		jobManager:        jobManager,
+		pipelineManager:   pipelineManager,
+		pluginManager:     pluginManager,
+		store:             store,
+		idempotencyWindow: idempotencyWindow,
+		router:            mux.NewRouter(),
 	}
+
+	// Les clés d'idempotence expirées pendant l'arrêt ne sont plus utiles
+	if purged, err := store.PurgeIdempotencyRecords(time.Now().Add(-idempotencyWindow)); err != nil {
+		logger.Error(err.Error())
+	} else if purged > 0 {
+		logger.Info(fmt.Sprintf("Purged %d expired idempotency keys", purged))
+	}
+
 	s.routes()
 	return s
 }
 
 func (s *Server) routes() {
 	s.router.HandleFunc("/jobs", authMiddleware(s.handleGetJobs)).Methods("GET")
-	s.router.HandleFunc("/jobs", authMiddleware(s.handleCreateJob)).Methods("POST")
+	s.router.HandleFunc("/jobs", authMiddleware(s.idempotent(s.lookupJob, s.handleCreateJob))).Methods("POST")
 	s.router.HandleFunc("/jobs/{id}", authMiddleware(s.handleGetJob)).Methods("GET")
+	s.router.HandleFunc("/jobs/{id}/cancel", authMiddleware(s.handleCancelJob)).Methods("POST")
+	s.router.HandleFunc("/jobs/{id}/rerun", authMiddleware(s.idempotent(s.lookupJob, s.handleRerunJob))).Methods("POST")
+	s.router.HandleFunc("/jobs/{id}/clone", authMiddleware(s.idempotent(s.lookupJob, s.handleCloneJob))).Methods("POST")
+	s.router.HandleFunc("/jobs/{id}/logs", authMiddleware(s.handleGetJobLogs)).Methods("GET")
+	s.router.HandleFunc("/jobs/{id}/output", authMiddleware(s.handleGetJobOutput)).Methods("GET")
+	s.router.HandleFunc("/jobs/{id}/attempts", authMiddleware(s.handleGetJobAttempts)).Methods("GET")
+	s.router.HandleFunc("/jobs/{id}/artifacts", authMiddleware(s.handleGetJobArtifacts)).Methods("GET")
+	s.router.HandleFunc("/artifacts/{sha}", authMiddleware(s.handleGetArtifact)).Methods("GET")
+	s.router.HandleFunc("/queue", authMiddleware(s.handleGetQueue)).Methods("GET")
+	s.router.HandleFunc("/workers", authMiddleware(s.handleGetWorkers)).Methods("GET")
+	s.router.HandleFunc("/workers", authMiddleware(s.handleResizeWorkers)).Methods("PUT")
+	s.router.HandleFunc("/locks", authMiddleware(s.handleGetLocks)).Methods("GET")
+	s.router.HandleFunc("/recovery", authMiddleware(s.handleGetRecovery)).Methods("GET")
+	s.router.HandleFunc("/templates", authMiddleware(s.handleGetTemplates)).Methods("GET")
+	s.router.HandleFunc("/templates", authMiddleware(s.handleSaveTemplate)).Methods("POST")
+	s.router.HandleFunc("/templates/{name}", authMiddleware(s.handleGetTemplate)).Methods("GET")
+	s.router.HandleFunc("/templates/{name}", authMiddleware(s.handleDeleteTemplate)).Methods("DELETE")
+	s.router.HandleFunc("/templates/{name}/run", authMiddleware(s.idempotent(s.lookupJob, s.handleRunTemplate))).Methods("POST")
 	s.router.HandleFunc("/pipelines", authMiddleware(s.handleGetPipelines)).Methods("GET")
-	s.router.HandleFunc("/pipelines", authMiddleware(s.handleCreatePipeline)).Methods("POST")
+	s.router.HandleFunc("/pipelines", authMiddleware(s.idempotent(s.lookupPipeline, s.handleCreatePipeline))).Methods("POST")
 	s.router.HandleFunc("/pipelines/{id}", authMiddleware(s.handleGetPipeline)).Methods("GET")
+	s.router.HandleFunc("/pipelines/{id}/context", authMiddleware(s.handleGetPipelineContext)).Methods("GET")
 	s.router.HandleFunc("/plugins", authMiddleware(s.handleGetPlugins)).Methods("GET")
 	s.router.HandleFunc("/plugins/{name}/execute", authMiddleware(s.handleExecutePlugin)).Methods("POST")
 }
@@ -51,21 +88,204 @@ func (s *Server) handleGetJobs(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, jobs)
 }
 
-func (s *Server) handleCreateJob(w http.ResponseWriter, r *http.Request) {
-	var jobReq struct {
-		ID      string   `json:"id"`
-		Command string   `json:"command"`
-		Args    []string `json:"args"`
+// jobRequest est la définition d'un job reçue par l'API
+type jobRequest struct {
+	ID               string              `json:"id"`
+	Command          string              `json:"command"`
+	Args             []string            `json:"args"`
+	Script           string              `json:"script"`
+	Interpreter      string              `json:"interpreter"`
+	Env              map[string]string   `json:"env"`
+	WorkingDir       string              `json:"working_dir"`
+	Stdin            string              `json:"stdin"`
+	Priority         *priorityValue      `json:"priority"`
+	Limits           *limitsRequest      `json:"limits"`
+	Timeout          string              `json:"timeout"`
+	GracePeriod      string              `json:"grace_period"` // Délai entre SIGTERM et SIGKILL
+	MaxRetries       *int                `json:"max_retries"`
+	RetryPolicy      *retryPolicyRequest `json:"retry_policy"`
+	Success          *successRequest     `json:"success"`
+	Locks            []lockRequest       `json:"locks"`
+	DependsOn        []string            `json:"depends_on"`
+	DependencyPolicy string              `json:"dependency_policy"` // skip (par défaut) ou fail
+	RecoveryPolicy   string              `json:"recovery_policy"`   // requeue ou lost
+	ResultFormat     string              `json:"result_format"`     // text (par défaut), json ou kv
+	StepName         string              `json:"step_name"`
+	Artifacts        []string            `json:"artifacts"` // Motifs relatifs au workspace du job
+	RunAsUser        string              `json:"run_as_user"`
+	RunAsGroup       string              `json:"run_as_group"`
+	Isolation        string              `json:"isolation"` // none (par défaut) ou sandbox
+	Host             string              `json:"host"`      // Hôte de l'inventaire SSH
+}
+
+// apply reporte les champs optionnels de la requête sur un job préparé
+func (req *jobRequest) apply(j *models.Job) error {
+	j.Script = req.Script
+	j.Interpreter = req.Interpreter
+	j.Env = req.Env
+	j.WorkingDir = req.WorkingDir
+	j.Stdin = req.Stdin
+	if req.Priority != nil {
+		j.Priority = int(*req.Priority)
+	}
+	if req.Timeout != "" {
+		timeout, err := time.ParseDuration(req.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout: %v", err)
+		}
+		j.Timeout = timeout
+	}
+	if req.GracePeriod != "" {
+		grace, err := time.ParseDuration(req.GracePeriod)
+		if err != nil {
+			return fmt.Errorf("invalid grace period: %v", err)
+		}
+		j.GracePeriod = grace
+	}
+	if req.MaxRetries != nil {
+		j.MaxRetries = *req.MaxRetries
+	}
+	if req.RetryPolicy != nil {
+		policy, err := req.RetryPolicy.toModel()
+		if err != nil {
+			return err
+		}
+		j.RetryPolicy = policy
 	}
+	if req.Limits != nil {
+		j.Limits = &models.ResourceLimits{
+			MemoryBytes:  req.Limits.MemoryBytes,
+			CPUs:         req.Limits.CPUs,
+			MaxProcesses: req.Limits.MaxProcesses,
+			MaxOpenFiles: req.Limits.MaxOpenFiles,
+		}
+	}
+	if req.Success != nil {
+		j.Success = &models.SuccessCriteria{
+			ExitCodes:          req.Success.ExitCodes,
+			StdoutMustMatch:    req.Success.StdoutMustMatch,
+			StdoutMustNotMatch: req.Success.StdoutMustNotMatch,
+		}
+	}
+	j.Locks = toResourceLocks(req.Locks)
+	j.DependsOn = req.DependsOn
+	j.DependencyPolicy = models.DependencyPolicy(req.DependencyPolicy)
+	j.RecoveryPolicy = models.RecoveryPolicy(req.RecoveryPolicy)
+	j.ResultFormat = models.ResultFormat(req.ResultFormat)
+	j.StepName = req.StepName
+	j.ArtifactGlobs = req.Artifacts
+	j.RunAsUser = req.RunAsUser
+	j.RunAsGroup = req.RunAsGroup
+	j.Isolation = models.Isolation(req.Isolation)
+	j.Host = req.Host
+	return nil
+}
+
+// lockRequest déclare un verrou nommé ; limit fixe le nombre de détenteurs simultanés (1 par défaut)
+type lockRequest struct {
+	Name  string `json:"name"`
+	Limit int    `json:"limit"`
+}
+
+func toResourceLocks(reqs []lockRequest) []models.ResourceLock {
+	if len(reqs) == 0 {
+		return nil
+	}
+	locks := make([]models.ResourceLock, 0, len(reqs))
+	for _, req := range reqs {
+		locks = append(locks, models.ResourceLock{Name: req.Name, Limit: req.Limit})
+	}
+	return locks
+}
+
+// limitsRequest est la représentation JSON des limites de ressources d'un job
+type limitsRequest struct {
+	MemoryBytes  int64   `json:"memory_bytes"`
+	CPUs         float64 `json:"cpus"`
+	MaxProcesses int     `json:"max_processes"`
+	MaxOpenFiles int     `json:"max_open_files"`
+}
+
+// successRequest est la représentation JSON des critères de succès d'un job
+type successRequest struct {
+	ExitCodes          []int    `json:"exit_codes"`
+	StdoutMustMatch    []string `json:"stdout_must_match"`
+	StdoutMustNotMatch []string `json:"stdout_must_not_match"`
+}
+
+// priorityValue accepte une priorité numérique ou un niveau nommé (low, normal, high, critical)
+type priorityValue int
+
+func (p *priorityValue) UnmarshalJSON(data []byte) error {
+	var number int
+	if err := json.Unmarshal(data, &number); err == nil {
+		*p = priorityValue(number)
+		return nil
+	}
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return fmt.Errorf("invalid priority: %s", data)
+	}
+	priority, err := job.ParsePriority(name)
+	if err != nil {
+		return err
+	}
+	*p = priorityValue(priority)
+	return nil
+}
+
+// retryPolicyRequest est la représentation JSON d'une politique de nouvelles tentatives
+type retryPolicyRequest struct {
+	Backoff          string   `json:"backoff"`
+	InitialDelay     string   `json:"initial_delay"`
+	MaxDelay         string   `json:"max_delay"`
+	Jitter           float64  `json:"jitter"`
+	RetryOnExitCodes []int    `json:"retry_on_exit_codes"`
+	RetryOnOutput    []string `json:"retry_on_output"`
+	NoRetryOnTimeout bool     `json:"no_retry_on_timeout"`
+}
+
+func (req *retryPolicyRequest) toModel() (*models.RetryPolicy, error) {
+	policy := &models.RetryPolicy{
+		Backoff:          models.BackoffStrategy(req.Backoff),
+		Jitter:           req.Jitter,
+		RetryOnExitCodes: req.RetryOnExitCodes,
+		RetryOnOutput:    req.RetryOnOutput,
+		NoRetryOnTimeout: req.NoRetryOnTimeout,
+	}
+	var err error
+	if req.InitialDelay != "" {
+		if policy.InitialDelay, err = time.ParseDuration(req.InitialDelay); err != nil {
+			return nil, fmt.Errorf("invalid retry initial delay: %v", err)
+		}
+	}
+	if req.MaxDelay != "" {
+		if policy.MaxDelay, err = time.ParseDuration(req.MaxDelay); err != nil {
+			return nil, fmt.Errorf("invalid retry max delay: %v", err)
+		}
+	}
+	if err := job.ValidateRetryPolicy(policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (s *Server) handleCreateJob(w http.ResponseWriter, r *http.Request) {
+	var jobReq jobRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&jobReq); err != nil {
 		respondError(w, http.StatusBadRequest, "Invalid request payload")
 		return
 	}
 
-	newJob, err := s.jobManager.CreateJob(jobReq.ID, jobReq.Command, jobReq.Args, "")
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, err.Error())
+	newJob := s.jobManager.PrepareJob(jobReq.ID, jobReq.Command, jobReq.Args, "")
+	if err := jobReq.apply(newJob); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := s.jobManager.SubmitJobFrom(newJob, requester(r)); err != nil {
+		respondSubmitError(w, err)
 		return
 	}
 
@@ -85,6 +305,174 @@ func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, job)
 }
 
+func (s *Server) handleGetJobAttempts(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	jobID := vars["id"]
+
+	if _, err := s.jobManager.GetJob(jobID); err != nil {
+		respondError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+
+	attempts, err := s.jobManager.GetAttempts(jobID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, attempts)
+}
+
+// queuedJob décrit un job en attente dans la file
+type queuedJob struct {
+	Position int       `json:"position"`
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Priority int       `json:"priority"`
+	QueuedAt time.Time `json:"queued_at"`
+	Wait     string    `json:"wait"`
+}
+
+func (s *Server) handleGetQueue(w http.ResponseWriter, r *http.Request) {
+	stats := s.jobManager.QueueStats()
+	pending := s.jobManager.PendingJobs()
+
+	now := time.Now()
+	jobs := make([]queuedJob, 0, len(pending))
+	for i, j := range pending {
+		jobs = append(jobs, queuedJob{
+			Position: i + 1,
+			ID:       j.ID,
+			Name:     j.Name,
+			Priority: j.Priority,
+			QueuedAt: j.QueuedAt,
+			Wait:     now.Sub(j.QueuedAt).Round(time.Second).String(),
+		})
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"depth":        stats.Depth,
+		"oldest_wait":  stats.OldestWait.Round(time.Second).String(),
+		"average_wait": stats.AverageWait.Round(time.Second).String(),
+		"jobs":         jobs,
+	})
+}
+
+// workerPoolStats décrit l'utilisation d'un pool de workers
+type workerPoolStats struct {
+	Size int `json:"size"`
+	Busy int `json:"busy"`
+}
+
+// workersRequest redimensionne les pools ; un champ absent laisse le pool inchangé
+type workersRequest struct {
+	Jobs      *int `json:"jobs"`
+	Pipelines *int `json:"pipelines"`
+}
+
+func (s *Server) handleGetWorkers(w http.ResponseWriter, r *http.Request) {
+	jobs := s.jobManager.WorkerStats()
+	pipelines := s.pipelineManager.WorkerStats()
+	respondJSON(w, http.StatusOK, map[string]workerPoolStats{
+		"jobs":      {Size: jobs.Size, Busy: jobs.Busy},
+		"pipelines": {Size: pipelines.Size, Busy: pipelines.Busy},
+	})
+}
+
+func (s *Server) handleResizeWorkers(w http.ResponseWriter, r *http.Request) {
+	var req workersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if (req.Jobs != nil && *req.Jobs < 1) || (req.Pipelines != nil && *req.Pipelines < 1) {
+		respondError(w, http.StatusBadRequest, "Worker count must be at least 1")
+		return
+	}
+
+	if req.Jobs != nil {
+		if err := s.jobManager.ResizeWorkers(*req.Jobs); err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if req.Pipelines != nil {
+		if err := s.pipelineManager.ResizeWorkers(*req.Pipelines); err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	s.handleGetWorkers(w, r)
+}
+
+// lockHolder décrit un job ou un pipeline qui tient ou attend un verrou
+type lockHolder struct {
+	Kind     string    `json:"kind"`
+	ID       string    `json:"id"`
+	Pipeline string    `json:"pipeline,omitempty"`
+	Since    time.Time `json:"since"`
+}
+
+// lockStatus décrit l'état d'un verrou nommé
+type lockStatus struct {
+	Name    string       `json:"name"`
+	Limit   int          `json:"limit"`
+	Holders []lockHolder `json:"holders"`
+	Waiters []lockHolder `json:"waiters"`
+}
+
+func (s *Server) handleGetLocks(w http.ResponseWriter, r *http.Request) {
+	snapshot := s.jobManager.Locks().Snapshot()
+	statuses := make([]lockStatus, 0, len(snapshot))
+	for _, st := range snapshot {
+		statuses = append(statuses, lockStatus{
+			Name:    st.Name,
+			Limit:   st.Limit,
+			Holders: toLockHolders(st.Holders),
+			Waiters: toLockHolders(st.Waiters),
+		})
+	}
+	respondJSON(w, http.StatusOK, statuses)
+}
+
+// recoveryReport résume les éléments repris au démarrage après un arrêt inattendu
+type recoveryReport struct {
+	Time     time.Time `json:"time"`
+	Requeued []string  `json:"requeued"`
+	Lost     []string  `json:"lost"`
+}
+
+func (s *Server) handleGetRecovery(w http.ResponseWriter, r *http.Request) {
+	jobs := s.jobManager.Recovery()
+	pipelines := s.pipelineManager.Recovery()
+	respondJSON(w, http.StatusOK, map[string]recoveryReport{
+		"jobs":      {Time: jobs.Time, Requeued: nonNil(jobs.Requeued), Lost: nonNil(jobs.Lost)},
+		"pipelines": {Time: pipelines.Time, Requeued: nonNil(pipelines.Requeued), Lost: nonNil(pipelines.Lost)},
+	})
+}
+
+// nonNil retourne une liste vide plutôt que nil afin qu'elle soit encodée en []
+func nonNil(ids []string) []string {
+	if ids == nil {
+		return []string{}
+	}
+	return ids
+}
+
+func toLockHolders(holders []lock.Holder) []lockHolder {
+	result := make([]lockHolder, 0, len(holders))
+	for _, h := range holders {
+		result = append(result, lockHolder{
+			Kind:     h.Owner.Kind,
+			ID:       h.Owner.ID,
+			Pipeline: h.Owner.Parent,
+			Since:    h.Since,
+		})
+	}
+	return result
+}
+
 func (s *Server) handleGetPipelines(w http.ResponseWriter, r *http.Request) {
 	pipelines := s.pipelineManager.GetPipelines()
 	respondJSON(w, http.StatusOK, pipelines)
@@ -92,9 +480,13 @@ func (s *Server) handleGetPipelines(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleCreatePipeline(w http.ResponseWriter, r *http.Request) {
 	var pipelineReq struct {
-		ID     string   `json:"id"`
-		Name   string   `json:"name"`
-		JobIDs []string `json:"job_ids"`
+		ID             string              `json:"id"`
+		Name           string              `json:"name"`
+		JobIDs         []string            `json:"job_ids"`
+		Jobs           []jobRequest        `json:"jobs"`
+		RetryPolicy    *retryPolicyRequest `json:"retry_policy"`
+		Locks          []lockRequest       `json:"locks"`
+		RecoveryPolicy string              `json:"recovery_policy"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&pipelineReq); err != nil {
@@ -102,6 +494,28 @@ func (s *Server) handleCreatePipeline(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recoveryPolicy := models.RecoveryPolicy(pipelineReq.RecoveryPolicy)
+	if err := job.ValidateRecoveryPolicy(recoveryPolicy); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	locks := toResourceLocks(pipelineReq.Locks)
+	if err := lock.Validate(locks); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var retryPolicy *models.RetryPolicy
+	if pipelineReq.RetryPolicy != nil {
+		policy, err := pipelineReq.RetryPolicy.toModel()
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		retryPolicy = policy
+	}
+
 	jobs := make([]*models.Job, 0, len(pipelineReq.JobIDs))
 	for _, jobID := range pipelineReq.JobIDs {
 		job, err := s.jobManager.GetJob(jobID)
@@ -112,14 +526,61 @@ func (s *Server) handleCreatePipeline(w http.ResponseWriter, r *http.Request) {
 		jobs = append(jobs, job)
 	}
 
+	if pipelineReq.ID == "" {
+		pipelineReq.ID = utils.GenerateID(8)
+	} else if err := job.ValidatePipelineID(pipelineReq.ID); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	} else if _, err := s.pipelineManager.GetPipeline(pipelineReq.ID); err == nil {
+		respondError(w, http.StatusConflict, fmt.Sprintf("Pipeline %s already exists", pipelineReq.ID))
+		return
+	}
+
+	// Les jobs définis dans le pipeline ne sont exécutés que comme étapes de celui-ci
+	inlineJobs := make([]*models.Job, 0, len(pipelineReq.Jobs))
+	for _, jobReq := range pipelineReq.Jobs {
+		stepJob := s.jobManager.PrepareJob(jobReq.ID, jobReq.Command, jobReq.Args, "")
+		if err := jobReq.apply(stepJob); err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		stepJob.PipelineID = pipelineReq.ID
+		inlineJobs = append(inlineJobs, stepJob)
+	}
+	steps := make(map[string]bool)
+	for _, stepJob := range append(append([]*models.Job(nil), jobs...), inlineJobs...) {
+		name := pipeline.StepName(stepJob)
+		if steps[name] {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("Step name %s is used more than once", name))
+			return
+		}
+		steps[name] = true
+	}
+	owned := make([]string, 0, len(inlineJobs))
+	for _, stepJob := range inlineJobs {
+		if err := s.jobManager.RegisterJobFrom(stepJob, requester(r)); err != nil {
+			s.discardSteps(owned)
+			respondSubmitError(w, err)
+			return
+		}
+		jobs = append(jobs, stepJob)
+		owned = append(owned, stepJob.ID)
+	}
+
 	newPipeline := &models.Pipeline{
-		ID:          pipelineReq.ID,
-		Name:        pipelineReq.Name,
-		Jobs:        jobs,
-		Status:      models.PipelineStatusPending,
-		ScheduledAt: time.Now().Add(1 * time.Minute),
+		ID:             pipelineReq.ID,
+		Name:           pipelineReq.Name,
+		Jobs:           jobs,
+		OwnedJobIDs:    owned,
+		Status:         models.PipelineStatusPending,
+		Context:        make(map[string]interface{}),
+		ScheduledAt:    time.Now().Add(1 * time.Minute),
+		RetryPolicy:    retryPolicy,
+		Locks:          locks,
+		RecoveryPolicy: recoveryPolicy,
 	}
 	if err := s.pipelineManager.AddPipeline(newPipeline); err != nil {
+		s.discardSteps(owned)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -127,6 +588,15 @@ func (s *Server) handleCreatePipeline(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusCreated, newPipeline)
 }
 
+// discardSteps supprime les étapes déjà enregistrées d'un pipeline dont la création a échoué
+func (s *Server) discardSteps(ids []string) {
+	for _, id := range ids {
+		if err := s.jobManager.DeleteStep(id); err != nil {
+			logger.Error(fmt.Sprintf("Failed to remove step %s of a rejected pipeline: %v", id, err))
+		}
+	}
+}
+
 func (s *Server) handleGetPipeline(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	pipelineID := vars["id"]
@@ -140,6 +610,22 @@ func (s *Server) handleGetPipeline(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, pipeline)
 }
 
+// handleGetPipelineContext retourne le contexte d'un pipeline, ou la valeur désignée par ?path=
+func (s *Server) handleGetPipelineContext(w http.ResponseWriter, r *http.Request) {
+	p, err := s.pipelineManager.GetPipeline(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Pipeline not found")
+		return
+	}
+
+	value, err := pipeline.LookupContext(p.Context, r.URL.Query().Get("path"))
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, value)
+}
+
 func respondJSON(w http.ResponseWriter, status int, payload interface{}) {
 	response, err := json.Marshal(payload)
 	if err != nil {
@@ -156,45 +642,34 @@ func respondError(w http.ResponseWriter, code int, message string) {
 	respondJSON(w, code, map[string]string{"error": message})
 }
 
-func (s *Server) handleStartJob(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	jobID := vars["id"]
-
-	job, err := s.jobManager.GetJob(jobID)
-	if err != nil {
-		respondError(w, http.StatusNotFound, "Job not found")
-		return
-	}
-
-	err = s.jobManager.AddJob(job)
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to start job: %v", err))
-		return
+// respondSubmitError répond à l'échec de la soumission d'un job : 400 pour une définition
+// refusée par la validation, 403 pour une identité d'exécution non autorisée, 500 sinon
+func respondSubmitError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, job.ErrInvalidJob):
+		code = http.StatusBadRequest
+	case errors.Is(err, job.ErrRunAsNotAllowed):
+		code = http.StatusForbidden
 	}
-
-	respondJSON(w, http.StatusOK, map[string]string{"status": "Job started"})
+	respondError(w, code, err.Error())
 }
 
-func (s *Server) handleStopJob(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleCancelJob(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	jobID := vars["id"]
 
-	job, err := s.jobManager.GetJob(jobID)
-	if err != nil {
+	if _, err := s.jobManager.GetJob(jobID); err != nil {
 		respondError(w, http.StatusNotFound, "Job not found")
 		return
 	}
 
-	// Implement job stopping logic here
-	// For now, we'll just update the status
-	job.Status = models.JobStatusFailed
-	err = s.jobManager.UpdateJob(job)
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to stop job: %v", err))
+	if err := s.jobManager.CancelJob(jobID); err != nil {
+		respondError(w, http.StatusConflict, fmt.Sprintf("Failed to cancel job: %v", err))
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]string{"status": "Job stopped"})
+	respondJSON(w, http.StatusOK, map[string]string{"status": "Job cancelled"})
 }
 
 func (s *Server) handleUpdatePipeline(w http.ResponseWriter, r *http.Request) {
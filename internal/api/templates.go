@@ -0,0 +1,157 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chrlesur/orchestrator/internal/job"
+	"github.com/chrlesur/orchestrator/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// templateRequest est la définition d'un modèle de job reçue par l'API
+type templateRequest struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Command     string                 `json:"command"`
+	Plugin      string                 `json:"plugin"`
+	Args        []string               `json:"args"`
+	Params      []templateParamRequest `json:"params"`
+	Timeout     string                 `json:"timeout"`
+	MaxRetries  *int                   `json:"max_retries"`
+}
+
+type templateParamRequest struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Required    bool   `json:"required"`
+	Default     string `json:"default"`
+	Description string `json:"description"`
+}
+
+func (req *templateRequest) toModel() (*models.JobTemplate, error) {
+	t := &models.JobTemplate{
+		Name:        req.Name,
+		Description: req.Description,
+		Command:     req.Command,
+		PluginName:  req.Plugin,
+		Args:        req.Args,
+		MaxRetries:  req.MaxRetries,
+	}
+	if req.Timeout != "" {
+		timeout, err := time.ParseDuration(req.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout: %v", err)
+		}
+		t.Timeout = timeout
+	}
+	for _, p := range req.Params {
+		t.Params = append(t.Params, models.TemplateParam{
+			Name:        p.Name,
+			Type:        p.Type,
+			Required:    p.Required,
+			Default:     p.Default,
+			Description: p.Description,
+		})
+	}
+	return t, nil
+}
+
+func (s *Server) handleGetTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := s.jobManager.GetTemplates()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, templates)
+}
+
+func (s *Server) handleSaveTemplate(w http.ResponseWriter, r *http.Request) {
+	var req templateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	t, err := req.toModel()
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := job.ValidateTemplate(t); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.jobManager.SaveTemplate(t); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, t)
+}
+
+func (s *Server) handleGetTemplate(w http.ResponseWriter, r *http.Request) {
+	t, err := s.jobManager.GetTemplate(mux.Vars(r)["name"])
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Template not found")
+		return
+	}
+	respondJSON(w, http.StatusOK, t)
+}
+
+func (s *Server) handleDeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	if err := s.jobManager.DeleteTemplate(mux.Vars(r)["name"]); err != nil {
+		respondError(w, http.StatusNotFound, "Template not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRunTemplate instancie un modèle avec les paramètres fournis et soumet le job
+func (s *Server) handleRunTemplate(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if _, err := s.jobManager.GetTemplate(name); err != nil {
+		respondError(w, http.StatusNotFound, "Template not found")
+		return
+	}
+
+	var req struct {
+		Params map[string]interface{} `json:"params"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	params := make(map[string]string, len(req.Params))
+	for key, value := range req.Params {
+		switch v := value.(type) {
+		case string:
+			params[key] = v
+		case json.Number:
+			params[key] = v.String()
+		case bool:
+			params[key] = strconv.FormatBool(v)
+		default:
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("Parameter %s must be a string, a number or a boolean", key))
+			return
+		}
+	}
+
+	newJob, err := s.jobManager.InstantiateTemplate(name, params)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.jobManager.SubmitJobFrom(newJob, requester(r)); err != nil {
+		respondSubmitError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, newJob)
+}
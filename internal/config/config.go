@@ -3,28 +3,148 @@ package config
 import (
 	"fmt"
 	"io/ioutil"
+	"regexp"
 	"time"
 
+	"github.com/chrlesur/orchestrator/internal/job"
+	"github.com/chrlesur/orchestrator/internal/models"
+	"github.com/chrlesur/orchestrator/internal/retention"
 	"gopkg.in/yaml.v2"
 )
 
 type Config struct {
 	Server struct {
-		Port int `yaml:"port"`
+		Port              int           `yaml:"port"`
+		IdempotencyWindow time.Duration `yaml:"idempotency_window"`
 	} `yaml:"server"`
 	Database struct {
 		Path string `yaml:"path"`
 	} `yaml:"database"`
 	Jobs struct {
 		DefaultTimeout time.Duration `yaml:"default_timeout"`
+		GracePeriod    time.Duration `yaml:"grace_period"`
 		MaxRetries     int           `yaml:"max_retries"`
+		LogDir         string        `yaml:"log_dir"`
+		WorkspaceDir   string        `yaml:"workspace_dir"`
+		CgroupRoot     string        `yaml:"cgroup_root"`
+		MaxOutputSize  int           `yaml:"max_output_size"`
+		OutputDir      string        `yaml:"output_dir"`
+		ArtifactDir    string        `yaml:"artifact_dir"`
+		Retry          RetryConfig   `yaml:"retry"`
+		RunAs          RunAsConfig   `yaml:"run_as"`
+		Sandbox        SandboxConfig `yaml:"sandbox"`
 	} `yaml:"jobs"`
-	Logging struct {
+	Workers struct {
+		Jobs      int `yaml:"jobs"`
+		Pipelines int `yaml:"pipelines"`
+	} `yaml:"workers"`
+	Recovery struct {
+		Jobs      models.RecoveryPolicy `yaml:"jobs"`
+		Pipelines models.RecoveryPolicy `yaml:"pipelines"`
+	} `yaml:"recovery"`
+	SSH       SSHConfig       `yaml:"ssh"`
+	Retention RetentionConfig `yaml:"retention"`
+	Logging   struct {
 		Level string `yaml:"level"`
 		File  string `yaml:"file"`
 	} `yaml:"logging"`
 }
 
+// RetryConfig décrit la politique de nouvelles tentatives appliquée par défaut aux jobs
+type RetryConfig struct {
+	Backoff          string        `yaml:"backoff"`
+	InitialDelay     time.Duration `yaml:"initial_delay"`
+	MaxDelay         time.Duration `yaml:"max_delay"`
+	Jitter           float64       `yaml:"jitter"`
+	RetryOnExitCodes []int         `yaml:"retry_on_exit_codes"`
+	RetryOnOutput    []string      `yaml:"retry_on_output"`
+	NoRetryOnTimeout bool          `yaml:"no_retry_on_timeout"`
+}
+
+// Policy convertit la configuration en politique de nouvelles tentatives
+func (r RetryConfig) Policy() models.RetryPolicy {
+	return models.RetryPolicy{
+		Backoff:          models.BackoffStrategy(r.Backoff),
+		InitialDelay:     r.InitialDelay,
+		MaxDelay:         r.MaxDelay,
+		Jitter:           r.Jitter,
+		RetryOnExitCodes: r.RetryOnExitCodes,
+		RetryOnOutput:    r.RetryOnOutput,
+		NoRetryOnTimeout: r.NoRetryOnTimeout,
+	}
+}
+
+// RunAsConfig liste les utilisateurs et groupes sous lesquels les jobs peuvent être lancés
+type RunAsConfig struct {
+	Users  []string `yaml:"users"`
+	Groups []string `yaml:"groups"`
+}
+
+// SandboxConfig liste les chemins de l'hôte visibles en lecture seule par les jobs isolés
+type SandboxConfig struct {
+	Paths []string `yaml:"paths"`
+}
+
+// SSHConfig décrit le client ssh et l'inventaire des hôtes sur lesquels des jobs peuvent être exécutés
+type SSHConfig struct {
+	Command string                   `yaml:"command"`
+	Hosts   map[string]SSHHostConfig `yaml:"hosts"`
+}
+
+// SSHHostConfig décrit un hôte de l'inventaire ; l'adresse est par défaut le nom de l'hôte
+type SSHHostConfig struct {
+	Address      string   `yaml:"address"`
+	Port         int      `yaml:"port"`
+	User         string   `yaml:"user"`
+	IdentityFile string   `yaml:"identity_file"`
+	Options      []string `yaml:"options"`
+}
+
+// Inventory convertit la configuration en inventaire des hôtes distants
+func (s SSHConfig) Inventory() map[string]job.SSHHost {
+	hosts := make(map[string]job.SSHHost, len(s.Hosts))
+	for name, h := range s.Hosts {
+		hosts[name] = job.SSHHost{
+			Address:      h.Address,
+			Port:         h.Port,
+			User:         h.User,
+			IdentityFile: h.IdentityFile,
+			Options:      h.Options,
+		}
+	}
+	return hosts
+}
+
+// RetentionConfig décrit la durée de conservation des jobs et pipelines terminés, par statut
+type RetentionConfig struct {
+	Interval  time.Duration            `yaml:"interval"`
+	Jobs      map[string]RetentionRule `yaml:"jobs"`
+	Pipelines map[string]RetentionRule `yaml:"pipelines"`
+}
+
+// RetentionRule conserve les éléments au plus max_age et au plus keep_last par nom (0 : sans limite)
+type RetentionRule struct {
+	MaxAge   time.Duration `yaml:"max_age"`
+	KeepLast int           `yaml:"keep_last"`
+}
+
+// Policy convertit la configuration en politique de conservation
+func (r RetentionConfig) Policy(idempotencyWindow time.Duration) retention.Policy {
+	policy := retention.Policy{
+		Interval:          r.Interval,
+		Jobs:              make(map[string]retention.Rule),
+		Pipelines:         make(map[string]retention.Rule),
+		IdempotencyWindow: idempotencyWindow,
+	}
+	for status, rule := range r.Jobs {
+		policy.Jobs[status] = retention.Rule{MaxAge: rule.MaxAge, KeepLast: rule.KeepLast}
+	}
+	for status, rule := range r.Pipelines {
+		policy.Pipelines[status] = retention.Rule{MaxAge: rule.MaxAge, KeepLast: rule.KeepLast}
+	}
+	return policy
+}
+
 func LoadConfig(configPath string) (*Config, error) {
 	config := &Config{}
 
@@ -54,6 +174,9 @@ func validateAndSetDefaults(config *Config) error {
 	if config.Server.Port == 0 {
 		config.Server.Port = 8080 // Port par défaut
 	}
+	if config.Server.IdempotencyWindow == 0 {
+		config.Server.IdempotencyWindow = 24 * time.Hour // Durée de validité des clés d'idempotence
+	}
 
 	// Valider le chemin de la base de données
 	if config.Database.Path == "" {
@@ -64,9 +187,108 @@ func validateAndSetDefaults(config *Config) error {
 	if config.Jobs.DefaultTimeout == 0 {
 		config.Jobs.DefaultTimeout = 5 * time.Minute // Timeout par défaut
 	}
+	if config.Jobs.GracePeriod == 0 {
+		config.Jobs.GracePeriod = 10 * time.Second // Délai entre SIGTERM et SIGKILL
+	}
+	if config.Jobs.GracePeriod < 0 {
+		return fmt.Errorf("le délai de grâce des jobs ne doit pas être négatif")
+	}
 	if config.Jobs.MaxRetries == 0 {
 		config.Jobs.MaxRetries = 3 // Nombre maximal de tentatives par défaut
 	}
+	switch models.BackoffStrategy(config.Jobs.Retry.Backoff) {
+	case "":
+		config.Jobs.Retry.Backoff = string(models.BackoffExponential) // Stratégie par défaut
+	case models.BackoffConstant, models.BackoffLinear, models.BackoffExponential:
+	default:
+		return fmt.Errorf("stratégie de nouvelle tentative inconnue: %s", config.Jobs.Retry.Backoff)
+	}
+	if config.Jobs.Retry.InitialDelay == 0 {
+		config.Jobs.Retry.InitialDelay = 2 * time.Second // Délai initial entre deux tentatives
+	}
+	if config.Jobs.Retry.Jitter < 0 || config.Jobs.Retry.Jitter > 1 {
+		return fmt.Errorf("le jitter des nouvelles tentatives doit être compris entre 0 et 1")
+	}
+	for _, pattern := range config.Jobs.Retry.RetryOnOutput {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("motif de nouvelle tentative invalide %q: %v", pattern, err)
+		}
+	}
+	if config.Jobs.LogDir == "" {
+		config.Jobs.LogDir = "./data/logs" // Répertoire des logs stdout/stderr des jobs
+	}
+	if config.Jobs.WorkspaceDir == "" {
+		config.Jobs.WorkspaceDir = "./data/workspaces" // Répertoires partagés des jobs et pipelines
+	}
+	if config.Jobs.CgroupRoot == "" {
+		config.Jobs.CgroupRoot = "/sys/fs/cgroup/orchestrator" // Cgroup v2 des jobs limités
+	}
+	if config.Jobs.MaxOutputSize == 0 {
+		config.Jobs.MaxOutputSize = 1024 * 1024 // Sortie conservée sur le job (une valeur négative la rend illimitée)
+	}
+	if config.Jobs.OutputDir == "" {
+		config.Jobs.OutputDir = "./data/outputs" // Sorties complètes dépassant max_output_size
+	}
+	if config.Jobs.ArtifactDir == "" {
+		config.Jobs.ArtifactDir = "./data/artifacts" // Magasin des fichiers déclarés par les jobs
+	}
+
+	// Valider et définir les valeurs par défaut pour les pools de workers
+	if config.Workers.Jobs == 0 {
+		config.Workers.Jobs = 5 // Workers exécutant les jobs
+	}
+	if config.Workers.Pipelines == 0 {
+		config.Workers.Pipelines = 3 // Workers exécutant les pipelines
+	}
+	if config.Workers.Jobs < 0 || config.Workers.Pipelines < 0 {
+		return fmt.Errorf("le nombre de workers doit être positif")
+	}
+
+	// Valider et définir les valeurs par défaut pour la reprise après un arrêt inattendu
+	for _, policy := range []*models.RecoveryPolicy{&config.Recovery.Jobs, &config.Recovery.Pipelines} {
+		switch *policy {
+		case "":
+			*policy = models.RecoveryLost // Les éléments interrompus ne sont pas réexécutés
+		case models.RecoveryRequeue, models.RecoveryLost:
+		default:
+			return fmt.Errorf("politique de reprise inconnue: %s", *policy)
+		}
+	}
+
+	// Valider et définir les valeurs par défaut pour l'exécution distante
+	if config.SSH.Command == "" {
+		config.SSH.Command = "ssh" // Client ssh du système
+	}
+	for name, host := range config.SSH.Hosts {
+		if host.Port < 0 || host.Port > 65535 {
+			return fmt.Errorf("port invalide pour l'hôte %s: %d", name, host.Port)
+		}
+	}
+
+	// Valider et définir les valeurs par défaut pour la conservation des jobs et pipelines
+	if config.Retention.Interval == 0 {
+		config.Retention.Interval = time.Hour // Intervalle entre deux nettoyages
+	}
+	for status, rule := range config.Retention.Jobs {
+		switch models.JobStatus(status) {
+		case models.JobStatusCompleted, models.JobStatusFailed, models.JobStatusCancelled, models.JobStatusSkipped, models.JobStatusLost:
+		default:
+			return fmt.Errorf("statut de job inconnu dans la politique de conservation: %s", status)
+		}
+		if rule.MaxAge < 0 || rule.KeepLast < 0 {
+			return fmt.Errorf("la règle de conservation des jobs %s ne doit pas être négative", status)
+		}
+	}
+	for status, rule := range config.Retention.Pipelines {
+		switch models.PipelineStatus(status) {
+		case models.PipelineStatusCompleted, models.PipelineStatusFailed, models.PipelineStatusLost:
+		default:
+			return fmt.Errorf("statut de pipeline inconnu dans la politique de conservation: %s", status)
+		}
+		if rule.MaxAge < 0 || rule.KeepLast < 0 {
+			return fmt.Errorf("la règle de conservation des pipelines %s ne doit pas être négative", status)
+		}
+	}
 
 	// Valider et définir les valeurs par défaut pour le logging
 	if config.Logging.Level == "" {
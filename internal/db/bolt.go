@@ -1,6 +1,7 @@
 package db
 
 import (
+    "bytes"
     "encoding/json"
     "fmt"
     "time"
@@ -11,6 +12,10 @@ import (
 
 var jobBucket = []byte("jobs")
 var pipelineBucket = []byte("pipelines")
+var attemptBucket = []byte("attempts")
+var templateBucket = []byte("templates")
+var idempotencyBucket = []byte("idempotency")
+var artifactBucket = []byte("artifacts")
 
 type Store struct {
 	db *bolt.DB
@@ -30,6 +35,22 @@ func NewStore(dbPath string) (*Store, error) {
 		if err != nil {
 			return fmt.Errorf("could not create pipelines bucket: %v", err)
 		}
+		_, err = tx.CreateBucketIfNotExists(attemptBucket)
+		if err != nil {
+			return fmt.Errorf("could not create attempts bucket: %v", err)
+		}
+		_, err = tx.CreateBucketIfNotExists(templateBucket)
+		if err != nil {
+			return fmt.Errorf("could not create templates bucket: %v", err)
+		}
+		_, err = tx.CreateBucketIfNotExists(idempotencyBucket)
+		if err != nil {
+			return fmt.Errorf("could not create idempotency bucket: %v", err)
+		}
+		_, err = tx.CreateBucketIfNotExists(artifactBucket)
+		if err != nil {
+			return fmt.Errorf("could not create artifacts bucket: %v", err)
+		}
 		return nil
 	})
 	if err != nil {
@@ -134,4 +155,243 @@ func (s *Store) GetAllPipelines() ([]*models.Pipeline, error) {
         return nil, fmt.Errorf("could not get pipelines: %v", err)
     }
     return pipelines, nil
-}
\ No newline at end of file
+}
+
+// DeleteJob supprime un job, l'historique de ses tentatives et ses artefacts enregistrés
+func (s *Store) DeleteJob(id string) error {
+    return s.db.Update(func(tx *bolt.Tx) error {
+        if err := tx.Bucket(jobBucket).Delete([]byte(id)); err != nil {
+            return err
+        }
+        if err := deletePrefix(tx.Bucket(attemptBucket), []byte(id+"/")); err != nil {
+            return err
+        }
+        return deletePrefix(tx.Bucket(artifactBucket), []byte(id+"/"))
+    })
+}
+
+// deletePrefix supprime les clés d'un bucket commençant par prefix
+func deletePrefix(b *bolt.Bucket, prefix []byte) error {
+    var keys [][]byte
+    c := b.Cursor()
+    for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+        keys = append(keys, append([]byte(nil), k...))
+    }
+    for _, k := range keys {
+        if err := b.Delete(k); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (s *Store) DeletePipeline(id string) error {
+    return s.db.Update(func(tx *bolt.Tx) error {
+        return tx.Bucket(pipelineBucket).Delete([]byte(id))
+    })
+}
+
+// attemptKey construit une clé triée par job puis par numéro de tentative
+func attemptKey(jobID string, number int) []byte {
+    return []byte(fmt.Sprintf("%s/%06d", jobID, number))
+}
+
+func (s *Store) SaveAttempt(a *models.JobAttempt) error {
+    return s.db.Update(func(tx *bolt.Tx) error {
+        b := tx.Bucket(attemptBucket)
+        encoded, err := json.Marshal(a)
+        if err != nil {
+            return fmt.Errorf("could not encode attempt %d of job %s: %v", a.Number, a.JobID, err)
+        }
+        return b.Put(attemptKey(a.JobID, a.Number), encoded)
+    })
+}
+
+func (s *Store) GetAttempts(jobID string) ([]*models.JobAttempt, error) {
+    attempts := []*models.JobAttempt{}
+    prefix := []byte(jobID + "/")
+    err := s.db.View(func(tx *bolt.Tx) error {
+        c := tx.Bucket(attemptBucket).Cursor()
+        for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+            var a models.JobAttempt
+            if err := json.Unmarshal(v, &a); err != nil {
+                return err
+            }
+            attempts = append(attempts, &a)
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, fmt.Errorf("could not get attempts of job %s: %v", jobID, err)
+    }
+    return attempts, nil
+}
+
+func (s *Store) SaveTemplate(t *models.JobTemplate) error {
+    return s.db.Update(func(tx *bolt.Tx) error {
+        b := tx.Bucket(templateBucket)
+        encoded, err := json.Marshal(t)
+        if err != nil {
+            return fmt.Errorf("could not encode template %s: %v", t.Name, err)
+        }
+        return b.Put([]byte(t.Name), encoded)
+    })
+}
+
+func (s *Store) GetTemplate(name string) (*models.JobTemplate, error) {
+    var t models.JobTemplate
+    err := s.db.View(func(tx *bolt.Tx) error {
+        b := tx.Bucket(templateBucket)
+        v := b.Get([]byte(name))
+        if v == nil {
+            return fmt.Errorf("template %s not found", name)
+        }
+        return json.Unmarshal(v, &t)
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &t, nil
+}
+
+func (s *Store) GetAllTemplates() ([]*models.JobTemplate, error) {
+    templates := []*models.JobTemplate{}
+    err := s.db.View(func(tx *bolt.Tx) error {
+        b := tx.Bucket(templateBucket)
+        return b.ForEach(func(k, v []byte) error {
+            var t models.JobTemplate
+            if err := json.Unmarshal(v, &t); err != nil {
+                return err
+            }
+            templates = append(templates, &t)
+            return nil
+        })
+    })
+    if err != nil {
+        return nil, fmt.Errorf("could not get templates: %v", err)
+    }
+    return templates, nil
+}
+
+func (s *Store) DeleteTemplate(name string) error {
+    return s.db.Update(func(tx *bolt.Tx) error {
+        return tx.Bucket(templateBucket).Delete([]byte(name))
+    })
+}
+
+func (s *Store) SaveIdempotencyRecord(rec *models.IdempotencyRecord) error {
+    return s.db.Update(func(tx *bolt.Tx) error {
+        b := tx.Bucket(idempotencyBucket)
+        encoded, err := json.Marshal(rec)
+        if err != nil {
+            return fmt.Errorf("could not encode idempotency key %s: %v", rec.Key, err)
+        }
+        return b.Put([]byte(rec.Key), encoded)
+    })
+}
+
+func (s *Store) GetIdempotencyRecord(key string) (*models.IdempotencyRecord, error) {
+    var rec models.IdempotencyRecord
+    err := s.db.View(func(tx *bolt.Tx) error {
+        b := tx.Bucket(idempotencyBucket)
+        v := b.Get([]byte(key))
+        if v == nil {
+            return fmt.Errorf("idempotency key %s not found", key)
+        }
+        return json.Unmarshal(v, &rec)
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &rec, nil
+}
+
+// PurgeIdempotencyRecords supprime les clés enregistrées avant before et retourne leur nombre
+func (s *Store) PurgeIdempotencyRecords(before time.Time) (int, error) {
+    var expired [][]byte
+    err := s.db.Update(func(tx *bolt.Tx) error {
+        b := tx.Bucket(idempotencyBucket)
+        err := b.ForEach(func(k, v []byte) error {
+            var rec models.IdempotencyRecord
+            if err := json.Unmarshal(v, &rec); err != nil {
+                return err
+            }
+            if rec.CreatedAt.Before(before) {
+                expired = append(expired, append([]byte(nil), k...))
+            }
+            return nil
+        })
+        if err != nil {
+            return err
+        }
+        // Les clés sont supprimées après le parcours, qui ne supporte pas les modifications
+        for _, k := range expired {
+            if err := b.Delete(k); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return 0, fmt.Errorf("could not purge idempotency keys: %v", err)
+    }
+    return len(expired), nil
+}
+
+func (s *Store) SaveArtifact(a *models.Artifact) error {
+    return s.db.Update(func(tx *bolt.Tx) error {
+        b := tx.Bucket(artifactBucket)
+        encoded, err := json.Marshal(a)
+        if err != nil {
+            return fmt.Errorf("could not encode artifact %s of job %s: %v", a.Path, a.JobID, err)
+        }
+        return b.Put([]byte(a.JobID+"/"+a.Path), encoded)
+    })
+}
+
+func (s *Store) GetArtifacts(jobID string) ([]*models.Artifact, error) {
+    artifacts := []*models.Artifact{}
+    prefix := []byte(jobID + "/")
+    err := s.db.View(func(tx *bolt.Tx) error {
+        c := tx.Bucket(artifactBucket).Cursor()
+        for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+            var a models.Artifact
+            if err := json.Unmarshal(v, &a); err != nil {
+                return err
+            }
+            artifacts = append(artifacts, &a)
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, fmt.Errorf("could not get artifacts of job %s: %v", jobID, err)
+    }
+    return artifacts, nil
+}
+
+// FindArtifact retourne un enregistrement désignant le contenu d'empreinte sha
+func (s *Store) FindArtifact(sha string) (*models.Artifact, error) {
+    var found *models.Artifact
+    err := s.db.View(func(tx *bolt.Tx) error {
+        return tx.Bucket(artifactBucket).ForEach(func(k, v []byte) error {
+            if found != nil {
+                return nil
+            }
+            var a models.Artifact
+            if err := json.Unmarshal(v, &a); err != nil {
+                return err
+            }
+            if a.SHA256 == sha {
+                found = &a
+            }
+            return nil
+        })
+    })
+    if err != nil {
+        return nil, fmt.Errorf("could not search artifacts: %v", err)
+    }
+    if found == nil {
+        return nil, fmt.Errorf("artifact %s not found", sha)
+    }
+    return found, nil
+}
@@ -0,0 +1,210 @@
+package job
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+	"github.com/chrlesur/orchestrator/pkg/logger"
+)
+
+var artifactSHA = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// ValidateArtifactGlobs vérifie que les motifs d'artefacts restent dans le workspace du job
+func ValidateArtifactGlobs(globs []string) error {
+	for _, glob := range globs {
+		if glob == "" {
+			return fmt.Errorf("artifact pattern must not be empty")
+		}
+		if filepath.IsAbs(glob) {
+			return fmt.Errorf("artifact pattern %s must be relative to the job workspace", glob)
+		}
+		for _, part := range strings.Split(filepath.ToSlash(glob), "/") {
+			if part == ".." {
+				return fmt.Errorf("artifact pattern %s must not leave the job workspace", glob)
+			}
+		}
+		if _, err := filepath.Match(glob, ""); err != nil {
+			return fmt.Errorf("invalid artifact pattern %s: %v", glob, err)
+		}
+	}
+	return nil
+}
+
+// ArtifactPath retourne l'emplacement du contenu d'empreinte sha dans le magasin d'artefacts
+func ArtifactPath(dir, sha string) string {
+	return filepath.Join(dir, sha[:2], sha)
+}
+
+// collectArtifacts copie dans le magasin les fichiers du workspace correspondant aux motifs
+// déclarés par le job. Le workspace appartient au job : la collecte, faite avec les droits de
+// l'orchestrateur, ne lit rien que le job n'ait pu y écrire. Une erreur de collecte est
+// journalisée sans changer l'issue du job
+func (r *Runner) collectArtifacts(j *models.Job) {
+	if len(j.ArtifactGlobs) == 0 || r.settings.ArtifactDir == "" || r.store == nil {
+		return
+	}
+	// Les motifs d'un job relu depuis la base ne sont pas passés par la validation de l'API
+	if err := ValidateArtifactGlobs(j.ArtifactGlobs); err != nil {
+		logger.Warning(fmt.Sprintf("Job %s: artifacts not collected: %v", j.ID, err))
+		return
+	}
+
+	if r.settings.WorkspaceDir == "" {
+		logger.Warning(fmt.Sprintf("Job %s: artifacts not collected: workspaces are disabled", j.ID))
+		return
+	}
+	root, err := WorkspacePath(r.settings.WorkspaceDir, j)
+	if err != nil {
+		logger.Warning(fmt.Sprintf("Job %s: artifacts not collected: %v", j.ID, err))
+		return
+	}
+
+	collected := 0
+	for _, glob := range j.ArtifactGlobs {
+		matches, err := filepath.Glob(filepath.Join(root, glob))
+		if err != nil {
+			logger.Warning(fmt.Sprintf("Job %s: invalid artifact pattern %s: %v", j.ID, glob, err))
+			continue
+		}
+		for _, match := range matches {
+			info, err := os.Lstat(match)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			rel, err := filepath.Rel(root, match)
+			if err != nil {
+				continue
+			}
+
+			sha, size, err := storeArtifact(r.settings.ArtifactDir, root, rel)
+			if err != nil {
+				logger.Warning(fmt.Sprintf("Job %s: could not store artifact %s: %v", j.ID, rel, err))
+				continue
+			}
+			artifact := &models.Artifact{
+				JobID:     j.ID,
+				Path:      filepath.ToSlash(rel),
+				SHA256:    sha,
+				Size:      size,
+				CreatedAt: time.Now(),
+			}
+			if err := r.store.SaveArtifact(artifact); err != nil {
+				logger.Error(fmt.Sprintf("Failed to save artifact %s of job %s: %v", rel, j.ID, err))
+				continue
+			}
+			collected++
+		}
+	}
+	if collected > 0 {
+		logger.Info(fmt.Sprintf("Job %s: %d artifacts collected", j.ID, collected))
+	}
+}
+
+// withinDir indique si path se trouve sous le répertoire dir
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// storeArtifact copie le fichier régulier rel du répertoire root dans le magasin sous son
+// empreinte SHA-256. Un contenu déjà présent n'est stocké qu'une fois
+func storeArtifact(dir, root, rel string) (string, int64, error) {
+	in, err := openBeneath(root, rel)
+	if err != nil {
+		return "", 0, err
+	}
+	defer in.Close()
+	// Un lien physique peut désigner un fichier que le job ne peut pas lire
+	info, err := in.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return "", 0, fmt.Errorf("%s is not a regular file", rel)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && stat.Nlink > 1 {
+		return "", 0, fmt.Errorf("%s has several hard links", rel)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", 0, err
+	}
+	tmp, err := ioutil.TempFile(dir, ".upload-*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), in)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	sha := hex.EncodeToString(hash.Sum(nil))
+	dest := ArtifactPath(dir, sha)
+	if _, err := os.Stat(dest); err == nil {
+		return sha, size, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", 0, err
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return "", 0, err
+	}
+	return sha, size, nil
+}
+
+// GetArtifacts retourne les artefacts collectés pour un job
+func (m *Manager) GetArtifacts(id string) ([]*models.Artifact, error) {
+	if _, err := m.GetJob(id); err != nil {
+		return nil, err
+	}
+	return m.store.GetArtifacts(id)
+}
+
+// OpenArtifact retourne le fichier du magasin contenant l'artefact d'empreinte sha
+// ainsi qu'un enregistrement qui le désigne
+func (m *Manager) OpenArtifact(sha string) (string, *models.Artifact, error) {
+	if m.settings.ArtifactDir == "" {
+		return "", nil, fmt.Errorf("artifacts are disabled")
+	}
+	if !artifactSHA.MatchString(sha) {
+		return "", nil, fmt.Errorf("invalid artifact checksum %q", sha)
+	}
+	artifact, err := m.store.FindArtifact(sha)
+	if err != nil {
+		return "", nil, err
+	}
+	path := ArtifactPath(m.settings.ArtifactDir, sha)
+	if _, err := os.Stat(path); err != nil {
+		return "", nil, fmt.Errorf("artifact %s not found", sha)
+	}
+	return path, artifact, nil
+}
+
+// removeArtifacts supprime du magasin les contenus qui ne sont plus référencés par aucun job
+func (m *Manager) removeArtifacts(artifacts []*models.Artifact) {
+	if m.settings.ArtifactDir == "" {
+		return
+	}
+	for _, a := range artifacts {
+		if _, err := m.store.FindArtifact(a.SHA256); err == nil {
+			continue
+		}
+		path := ArtifactPath(m.settings.ArtifactDir, a.SHA256)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			logger.Warning(fmt.Sprintf("Could not remove artifact %s: %v", a.SHA256, err))
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package job
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// openBeneath ouvre le fichier rel sous root sans suivre aucun lien symbolique. Chaque
+// composant est ouvert relativement au répertoire précédent : un répertoire remplacé par un
+// lien après la sélection du fichier ne fait pas sortir de root
+func openBeneath(root, rel string) (*os.File, error) {
+	fd, err := syscall.Open(root, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: root, Err: err}
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	for i, part := range parts {
+		if part == ".." {
+			syscall.Close(fd)
+			return nil, fmt.Errorf("%s leaves %s", rel, root)
+		}
+		// Le fichier final est ouvert sans blocage au cas où il aurait été remplacé par un tube
+		flags := syscall.O_RDONLY | syscall.O_NOFOLLOW | syscall.O_CLOEXEC | syscall.O_NONBLOCK
+		if i < len(parts)-1 {
+			flags |= syscall.O_DIRECTORY
+		}
+		next, err := syscall.Openat(fd, part, flags, 0)
+		syscall.Close(fd)
+		if err != nil {
+			return nil, &os.PathError{Op: "open", Path: filepath.Join(root, rel), Err: err}
+		}
+		fd = next
+	}
+	return os.NewFile(uintptr(fd), filepath.Join(root, rel)), nil
+}
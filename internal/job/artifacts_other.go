@@ -0,0 +1,25 @@
+//go:build !linux
+// +build !linux
+
+package job
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// openBeneath ouvre le fichier rel sous root sans suivre de lien symbolique. Hors de Linux, les
+// répertoires traversés sont vérifiés avant l'ouverture, sans protection contre leur remplacement
+func openBeneath(root, rel string) (*os.File, error) {
+	base, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(base, rel)
+	if resolved, err := filepath.EvalSymlinks(path); err != nil || resolved != path {
+		return nil, fmt.Errorf("%s leaves %s through a link", rel, root)
+	}
+	return os.OpenFile(path, os.O_RDONLY|syscall.O_NOFOLLOW|syscall.O_NONBLOCK, 0)
+}
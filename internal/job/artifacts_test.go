@@ -0,0 +1,104 @@
+package job
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateArtifactGlobs(t *testing.T) {
+	tests := []struct {
+		name    string
+		globs   []string
+		wantErr bool
+	}{
+		{"none", nil, false},
+		{"relative patterns", []string{"dist/*.tar.gz", "report.html", "logs/**/out.txt"}, false},
+		{"current directory", []string{"./dist/*"}, false},
+		{"empty pattern", []string{""}, true},
+		{"absolute pattern", []string{"/etc/passwd"}, true},
+		{"parent directory", []string{"../other/*"}, true},
+		{"parent directory inside", []string{"dist/../../secret"}, true},
+		{"invalid pattern", []string{"dist/[a"}, true},
+	}
+	for _, tt := range tests {
+		if err := ValidateArtifactGlobs(tt.globs); (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestStoreArtifactConfinement(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "workspace")
+	outside := filepath.Join(dir, "outside")
+	for _, d := range []string{filepath.Join(root, "dist"), outside} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		filepath.Join(root, "report.txt"):       "report",
+		filepath.Join(root, "dist", "app.bin"):  "binary",
+		filepath.Join(outside, "secret"):        "secret",
+		filepath.Join(outside, "linked-secret"): "linked",
+	}
+	for path, content := range files {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		filepath.Join(root, "secret-link"): filepath.Join(outside, "secret"),
+		filepath.Join(root, "escape"):      outside,
+		filepath.Join(root, "local-link"):  filepath.Join(root, "report.txt"),
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Link(filepath.Join(outside, "linked-secret"), filepath.Join(root, "hardlink")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		rel     string
+		want    string
+		wantErr bool
+	}{
+		{"file of the workspace", "report.txt", "report", false},
+		{"nested file", "dist/app.bin", "binary", false},
+		{"symbolic link to an outside file", "secret-link", "", true},
+		{"symbolic link inside the workspace", "local-link", "", true},
+		{"file under a linked directory", "escape/secret", "", true},
+		{"hard link", "hardlink", "", true},
+		{"parent directory", "../outside/secret", "", true},
+		{"directory", "dist", "", true},
+		{"missing file", "missing.txt", "", true},
+	}
+	store := filepath.Join(dir, "artifacts")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sha, size, err := storeArtifact(store, root, tt.rel)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			sum := sha256.Sum256([]byte(tt.want))
+			if sha != hex.EncodeToString(sum[:]) || size != int64(len(tt.want)) {
+				t.Fatalf("artifact = %s (%d bytes), want the content %q", sha, size, tt.want)
+			}
+			stored, err := ioutil.ReadFile(ArtifactPath(store, sha))
+			if err != nil || string(stored) != tt.want {
+				t.Fatalf("stored content = %q, %v; want %q", stored, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package job
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+	"github.com/chrlesur/orchestrator/pkg/logger"
+)
+
+// ErrRunAsNotAllowed signale une identité d'exécution absente de la liste autorisée
+var ErrRunAsNotAllowed = errors.New("run-as identity not allowed")
+
+// AuthorizeRunAs vérifie que l'utilisateur et le groupe demandés par un job figurent dans
+// les listes autorisées par la configuration et existent sur la machine. Chaque refus est
+// journalisé avec l'origine de la demande
+func (m *Manager) AuthorizeRunAs(j *models.Job, requester string) error {
+	if j.RunAsUser == "" && j.RunAsGroup == "" {
+		return nil
+	}
+
+	err := m.checkRunAs(j)
+	if err != nil {
+		logger.Warning(fmt.Sprintf("Audit: rejected job %s (%s) requested by %s to run as user=%q group=%q: %v",
+			j.ID, j.Name, requester, j.RunAsUser, j.RunAsGroup, err))
+	}
+	return err
+}
+
+func (m *Manager) checkRunAs(j *models.Job) error {
+	if j.RunAsUser != "" && !contains(m.settings.RunAsUsers, j.RunAsUser) {
+		return fmt.Errorf("%w: user %s", ErrRunAsNotAllowed, j.RunAsUser)
+	}
+	if j.RunAsGroup != "" && !contains(m.settings.RunAsGroups, j.RunAsGroup) {
+		return fmt.Errorf("%w: group %s", ErrRunAsNotAllowed, j.RunAsGroup)
+	}
+	_, err := resolveCredential(j.RunAsUser, j.RunAsGroup)
+	return err
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveCredential convertit un utilisateur et un groupe, donnés par nom ou par identifiant,
+// en identité de processus. Sans groupe, le groupe principal de l'utilisateur est utilisé.
+// Retourne nil lorsque le job conserve l'identité de l'orchestrateur
+func resolveCredential(userName, groupName string) (*syscall.Credential, error) {
+	if userName == "" && groupName == "" {
+		return nil, nil
+	}
+	cred := &syscall.Credential{Uid: uint32(os.Getuid()), Gid: uint32(os.Getgid())}
+
+	if userName != "" {
+		u, err := user.Lookup(userName)
+		if err != nil {
+			u, err = user.LookupId(userName)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unknown user %s", userName)
+		}
+		uid, err := strconv.ParseUint(u.Uid, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported uid %s for user %s", u.Uid, userName)
+		}
+		gid, err := strconv.ParseUint(u.Gid, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported gid %s for user %s", u.Gid, userName)
+		}
+		cred.Uid = uint32(uid)
+		cred.Gid = uint32(gid)
+
+		// Les groupes secondaires de l'utilisateur remplacent ceux de l'orchestrateur
+		if ids, err := u.GroupIds(); err == nil {
+			for _, id := range ids {
+				if g, err := strconv.ParseUint(id, 10, 32); err == nil {
+					cred.Groups = append(cred.Groups, uint32(g))
+				}
+			}
+		}
+	}
+
+	if groupName != "" {
+		g, err := user.LookupGroup(groupName)
+		if err != nil {
+			g, err = user.LookupGroupId(groupName)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unknown group %s", groupName)
+		}
+		gid, err := strconv.ParseUint(g.Gid, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported gid %s for group %s", g.Gid, groupName)
+		}
+		cred.Gid = uint32(gid)
+	}
+	return cred, nil
+}
+
+// grantAccess donne à l'identité d'un job son fichier de script et son répertoire de
+// travail propre. Le répertoire partagé d'un pipeline reste à l'orchestrateur
+func (r *Runner) grantAccess(j *models.Job, args []string, cred *syscall.Credential) error {
+	if IsScript(j) && len(args) > 0 {
+		if err := os.Chown(args[0], int(cred.Uid), int(cred.Gid)); err != nil {
+			return fmt.Errorf("could not give the script to user %d: %v", cred.Uid, err)
+		}
+	}
+	if r.settings.WorkspaceDir != "" && j.PipelineID == "" {
+		workspace, err := WorkspacePath(r.settings.WorkspaceDir, j)
+		if err != nil {
+			return err
+		}
+		if err := os.Chown(workspace, int(cred.Uid), int(cred.Gid)); err != nil {
+			return fmt.Errorf("could not give the workspace to user %d: %v", cred.Uid, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package job
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+	"github.com/chrlesur/orchestrator/pkg/logger"
+)
+
+// ValidateDependencyPolicy vérifie la politique appliquée lorsqu'une dépendance échoue
+func ValidateDependencyPolicy(policy models.DependencyPolicy) error {
+	switch policy {
+	case "", models.DependencyPolicySkip, models.DependencyPolicyFail:
+		return nil
+	}
+	return fmt.Errorf("unknown dependency policy: %s", policy)
+}
+
+// checkDependencies vérifie que les dépendances d'un job existent et ne forment pas de cycle.
+// Doit être appelée avec m.mu verrouillé
+func (m *Manager) checkDependencies(j *models.Job) error {
+	seen := make(map[string]bool)
+	for _, id := range j.DependsOn {
+		if id == j.ID {
+			return fmt.Errorf("job %s cannot depend on itself", j.ID)
+		}
+		if seen[id] {
+			return fmt.Errorf("dependency %s is declared more than once", id)
+		}
+		seen[id] = true
+
+		upstream, exists := m.jobs[id]
+		if !exists {
+			return fmt.Errorf("dependency %s not found", id)
+		}
+		if upstream.PipelineID != "" {
+			return fmt.Errorf("dependency %s is a pipeline step", id)
+		}
+	}
+
+	if path := m.findCycle(j); path != nil {
+		return fmt.Errorf("dependency cycle detected: %v", path)
+	}
+	return nil
+}
+
+// findCycle parcourt le graphe des dépendances depuis j et retourne le chemin
+// revenant à j s'il existe. Doit être appelée avec m.mu verrouillé
+func (m *Manager) findCycle(j *models.Job) []string {
+	visited := make(map[string]bool)
+	var visit func(id string, path []string) []string
+	visit = func(id string, path []string) []string {
+		if id == j.ID {
+			return append(path, id)
+		}
+		if visited[id] {
+			return nil
+		}
+		visited[id] = true
+
+		upstream, exists := m.jobs[id]
+		if !exists {
+			return nil
+		}
+		for _, dep := range upstream.DependsOn {
+			if cycle := visit(dep, append(path, id)); cycle != nil {
+				return cycle
+			}
+		}
+		return nil
+	}
+
+	for _, dep := range j.DependsOn {
+		if cycle := visit(dep, []string{j.ID}); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// trackDependencies enregistre j dans le graphe et indique s'il peut être mis en file
+// immédiatement. Doit être appelée avec m.mu verrouillé
+func (m *Manager) trackDependencies(j *models.Job) bool {
+	for _, id := range j.DependsOn {
+		m.dependents[id] = append(m.dependents[id], j.ID)
+	}
+	if len(j.DependsOn) == 0 {
+		return true
+	}
+
+	j.Status = models.JobStatusBlocked
+	return false
+}
+
+// resolveDependents réévalue les jobs bloqués par id après la fin de celui-ci
+func (m *Manager) resolveDependents(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.resolve(m.dependents[id])
+}
+
+// resolve met en file les jobs dont toutes les dépendances ont abouti et applique la
+// politique de dépendance à ceux dont une dépendance a échoué. Les jobs ainsi
+// terminés débloquent à leur tour leurs dépendants. Doit être appelée avec m.mu verrouillé
+func (m *Manager) resolve(ids []string) {
+	for len(ids) > 0 {
+		j, exists := m.jobs[ids[0]]
+		ids = ids[1:]
+		if !exists || j.Status != models.JobStatusBlocked {
+			continue
+		}
+
+		ready, failed := m.dependencyState(j)
+		switch {
+		case failed != "":
+			m.abandon(j, failed)
+			ids = append(ids, m.dependents[j.ID]...)
+		case ready:
+			logger.Info(fmt.Sprintf("Dependencies of job %s completed, queueing it", j.ID))
+			j.Status = models.JobStatusPending
+			j.QueuedAt = time.Now()
+			if err := m.store.SaveJob(j); err != nil {
+				logger.Error(fmt.Sprintf("Failed to save job %s: %v", j.ID, err))
+			}
+			m.enqueue(j)
+		}
+	}
+}
+
+// dependencyState indique si toutes les dépendances de j ont abouti, ou retourne
+// l'identifiant de la première dépendance terminée sans succès
+func (m *Manager) dependencyState(j *models.Job) (bool, string) {
+	ready := true
+	for _, id := range j.DependsOn {
+		upstream, exists := m.jobs[id]
+		if !exists {
+			return false, id
+		}
+		switch upstream.Status {
+		case models.JobStatusCompleted:
+		case models.JobStatusFailed, models.JobStatusCancelled, models.JobStatusSkipped, models.JobStatusLost:
+			return false, id
+		default:
+			ready = false
+		}
+	}
+	return ready, ""
+}
+
+// abandon termine un job bloqué dont la dépendance upstream n'a pas abouti
+func (m *Manager) abandon(j *models.Job, upstream string) {
+	j.Error = fmt.Errorf("dependency %s did not complete", upstream)
+	j.EndTime = time.Now()
+	if j.DependencyPolicy == models.DependencyPolicyFail {
+		j.Status = models.JobStatusFailed
+		j.FailureReason = models.FailureUpstream
+	} else {
+		j.Status = models.JobStatusSkipped
+	}
+	logger.Warning(fmt.Sprintf("Job %s %s: dependency %s did not complete", j.ID, j.Status, upstream))
+
+	if err := m.store.SaveJob(j); err != nil {
+		logger.Error(fmt.Sprintf("Failed to save job %s: %v", j.ID, err))
+	}
+}
+
+// restoreDependencies reconstruit le graphe des dépendances au démarrage et débloque
+// les jobs dont les dépendances se sont terminées avant l'arrêt
+func (m *Manager) restoreDependencies(jobs []*models.Job) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var blocked []string
+	for _, j := range jobs {
+		for _, id := range j.DependsOn {
+			m.dependents[id] = append(m.dependents[id], j.ID)
+		}
+		if j.Status == models.JobStatusBlocked {
+			blocked = append(blocked, j.ID)
+		}
+	}
+	m.resolve(blocked)
+}
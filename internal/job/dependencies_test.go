@@ -0,0 +1,112 @@
+package job
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chrlesur/orchestrator/internal/db"
+	"github.com/chrlesur/orchestrator/internal/models"
+)
+
+// testManager démarre un gestionnaire à un worker sur une base temporaire
+func testManager(t *testing.T, settings Settings) *Manager {
+	dir := t.TempDir()
+	store, err := db.NewStore(filepath.Join(dir, "orchestrator.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(store.Close)
+	settings.DefaultTimeout = time.Minute
+	settings.LogDir = filepath.Join(dir, "logs")
+	m := NewManager(1, store, settings, nil)
+	t.Cleanup(m.Shutdown)
+	return m
+}
+
+func TestCheckDependencies(t *testing.T) {
+	m := &Manager{jobs: map[string]*models.Job{
+		"a":    {ID: "a"},
+		"b":    {ID: "b", DependsOn: []string{"a"}},
+		"c":    {ID: "c", DependsOn: []string{"b", "new"}},
+		"step": {ID: "step", PipelineID: "p"},
+	}}
+
+	tests := []struct {
+		name      string
+		dependsOn []string
+		wantErr   bool
+	}{
+		{"no dependency", nil, false},
+		{"existing jobs", []string{"a", "b"}, false},
+		{"itself", []string{"new"}, true},
+		{"declared twice", []string{"a", "a"}, true},
+		{"unknown job", []string{"missing"}, true},
+		{"pipeline step", []string{"step"}, true},
+		{"cycle through other jobs", []string{"c"}, true},
+	}
+	for _, tt := range tests {
+		err := m.checkDependencies(&models.Job{ID: "new", DependsOn: tt.dependsOn})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestDependencyPolicies(t *testing.T) {
+	tests := []struct {
+		name       string
+		upstream   string
+		policy     models.DependencyPolicy
+		wantStatus models.JobStatus
+		wantReason string
+		downstream models.JobStatus
+	}{
+		{"upstream completed", "true", "", models.JobStatusCompleted, "", models.JobStatusCompleted},
+		{"skip by default", "false", "", models.JobStatusSkipped, "", models.JobStatusSkipped},
+		{"skip policy", "false", models.DependencyPolicySkip, models.JobStatusSkipped, "", models.JobStatusSkipped},
+		{"fail policy", "false", models.DependencyPolicyFail, models.JobStatusFailed, models.FailureUpstream, models.JobStatusSkipped},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testManager(t, Settings{})
+			upstream := m.PrepareJob("upstream", tt.upstream, nil, "")
+			upstream.MaxRetries = 0
+			if err := m.SubmitJob(upstream); err != nil {
+				t.Fatal(err)
+			}
+			dependent := m.PrepareJob("dependent", "true", nil, "")
+			dependent.DependsOn = []string{upstream.ID}
+			dependent.DependencyPolicy = tt.policy
+			if err := m.SubmitJob(dependent); err != nil {
+				t.Fatal(err)
+			}
+			// Un job bloqué transmet son abandon à ses propres dépendants
+			downstream := m.PrepareJob("downstream", "true", nil, "")
+			downstream.DependsOn = []string{dependent.ID}
+			if err := m.SubmitJob(downstream); err != nil {
+				t.Fatal(err)
+			}
+
+			// Les états sont relus dans la base, les jobs du gestionnaire étant modifiés par les workers
+			waitFor(t, func() bool {
+				got, err := m.store.GetJob(downstream.ID)
+				return err == nil && IsFinished(got.Status)
+			})
+			got, err := m.store.GetJob(dependent.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != tt.wantStatus || got.FailureReason != tt.wantReason {
+				t.Fatalf("dependent = %s (%q), want %s (%q)", got.Status, got.FailureReason, tt.wantStatus, tt.wantReason)
+			}
+			last, err := m.store.GetJob(downstream.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if last.Status != tt.downstream {
+				t.Fatalf("downstream job = %s, want %s", last.Status, tt.downstream)
+			}
+		})
+	}
+}
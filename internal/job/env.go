@@ -0,0 +1,125 @@
+package job
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+)
+
+// Variables d'environnement injectées par l'orchestrateur dans chaque job
+const (
+	EnvJobID      = "ORCH_JOB_ID"
+	EnvAttempt    = "ORCH_ATTEMPT"
+	EnvPipelineID = "ORCH_PIPELINE_ID"
+	EnvWorkspace  = "ORCH_WORKSPACE"
+
+	reservedEnvPrefix = "ORCH_"
+)
+
+// ValidateEnv vérifie les variables d'environnement déclarées par un job
+func ValidateEnv(env map[string]string) error {
+	for key := range env {
+		if key == "" || strings.ContainsAny(key, "= \t\n") {
+			return fmt.Errorf("invalid environment variable name %q", key)
+		}
+		if strings.HasPrefix(key, reservedEnvPrefix) {
+			return fmt.Errorf("environment variable %s uses the reserved prefix %s", key, reservedEnvPrefix)
+		}
+	}
+	return nil
+}
+
+// workspaceID restreint les identifiants qui nomment un répertoire de travail
+var workspaceID = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ValidatePipelineID vérifie qu'un identifiant de pipeline choisi par le client peut nommer
+// son répertoire de travail
+func ValidatePipelineID(id string) error {
+	if !workspaceID.MatchString(id) {
+		return fmt.Errorf("invalid pipeline ID %q: only letters, digits, '-' and '_' are allowed", id)
+	}
+	return nil
+}
+
+// WorkspacePath retourne le répertoire de travail partagé d'un job : celui de son pipeline
+// lorsqu'il en fait partie, sinon un répertoire propre au job
+func WorkspacePath(dir string, j *models.Job) (string, error) {
+	if j.PipelineID != "" {
+		return PipelineWorkspacePath(dir, j.PipelineID)
+	}
+	return confinedPath(filepath.Join(dir, "jobs"), j.ID)
+}
+
+// PipelineWorkspacePath retourne le répertoire de travail partagé par les étapes d'un pipeline
+func PipelineWorkspacePath(dir, pipelineID string) (string, error) {
+	return confinedPath(filepath.Join(dir, "pipelines"), pipelineID)
+}
+
+// confinedPath retourne le sous-répertoire id de root et refuse tout chemin qui n'y serait pas
+func confinedPath(root, id string) (string, error) {
+	path := filepath.Join(root, id)
+	if !workspaceID.MatchString(id) || filepath.Dir(path) != filepath.Clean(root) {
+		return "", fmt.Errorf("invalid workspace identifier %q", id)
+	}
+	return path, nil
+}
+
+// sandboxEnvironment remplace l'environnement de l'orchestrateur pour les jobs isolés, qui
+// n'héritent ni de ses secrets ni de ses chemins
+var sandboxEnvironment = []string{
+	"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
+	"HOME=/tmp",
+	"TMPDIR=/tmp",
+}
+
+// environment construit l'environnement du processus : celui de l'orchestrateur, ou un
+// environnement minimal dans un bac à sable, les variables du job puis les variables propres
+// à l'orchestrateur
+func (r *Runner) environment(j *models.Job) ([]string, error) {
+	base := os.Environ()
+	if j.Isolation == models.IsolationSandbox {
+		base = sandboxEnvironment
+	}
+	env := append(append([]string(nil), base...), jobEnvironment(j)...)
+
+	if r.settings.WorkspaceDir != "" {
+		workspace, err := WorkspacePath(r.settings.WorkspaceDir, j)
+		if err == nil {
+			workspace, err = filepath.Abs(workspace)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(workspace, 0755); err != nil {
+			return nil, fmt.Errorf("could not create workspace: %v", err)
+		}
+		env = append(env, EnvWorkspace+"="+workspace)
+	}
+
+	return env, nil
+}
+
+// jobEnvironment retourne les variables du job, triées, suivies des variables propres à l'orchestrateur
+func jobEnvironment(j *models.Job) []string {
+	keys := make([]string, 0, len(j.Env))
+	for key := range j.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	env := make([]string, 0, len(keys)+3)
+	for _, key := range keys {
+		env = append(env, key+"="+j.Env[key])
+	}
+	return append(env,
+		EnvJobID+"="+j.ID,
+		EnvAttempt+"="+strconv.Itoa(j.Attempt),
+		EnvPipelineID+"="+j.PipelineID,
+	)
+}
@@ -0,0 +1,81 @@
+package job
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+)
+
+// Executor démarre les tentatives d'un job dans un environnement d'exécution donné :
+// processus local, plugin ou hôte distant
+type Executor interface {
+	// Start démarre une tentative. stdout et stderr reçoivent la sortie au fil de l'eau
+	Start(j *models.Job, stdout, stderr io.Writer) (Process, error)
+}
+
+// Process est une tentative démarrée par un Executor
+type Process interface {
+	// Wait attend la fin de la tentative. Un code de sortie non nul n'est pas une erreur :
+	// l'erreur signale une tentative qui n'a pas pu être menée à son terme
+	Wait() (ExitStatus, error)
+	// Terminate demande à la tentative de s'arrêter d'elle-même, par SIGTERM pour un processus
+	Terminate()
+	// Cancel interrompt la tentative ; Wait retourne ensuite sans attendre sa fin normale
+	Cancel()
+}
+
+// ExitStatus décrit la fin d'une tentative
+type ExitStatus struct {
+	Code          int    // Code de sortie, -1 lorsque la tentative n'a pas terminé normalement
+	Signal        string // Signal ayant terminé le processus
+	FailureReason string // Limite de ressources dépassée, le cas échéant
+}
+
+// String décrit la fin de la tentative à la manière de exec.ExitError
+func (s ExitStatus) String() string {
+	if s.Signal != "" {
+		return "signal: " + s.Signal
+	}
+	return fmt.Sprintf("exit status %d", s.Code)
+}
+
+// executor choisit l'environnement d'exécution d'un job
+func (r *Runner) executor(j *models.Job) (Executor, error) {
+	switch {
+	case j.PluginName != "":
+		if r.plugins == nil {
+			return nil, fmt.Errorf("plugins are not available to run job %s", j.ID)
+		}
+		return &pluginExecutor{plugins: r.plugins}, nil
+	case j.Host != "":
+		host, ok := r.settings.SSHHosts[j.Host]
+		if !ok {
+			return nil, fmt.Errorf("unknown host %s", j.Host)
+		}
+		return &sshExecutor{command: r.settings.SSHCommand, name: j.Host, host: host}, nil
+	}
+	return &localExecutor{runner: r}, nil
+}
+
+// ValidateHost vérifie que l'hôte distant d'un job figure dans l'inventaire et que le job
+// n'utilise pas de fonctionnalité propre à l'exécution locale
+func (m *Manager) ValidateHost(j *models.Job) error {
+	if j.Host == "" {
+		return nil
+	}
+	if _, ok := m.settings.SSHHosts[j.Host]; !ok {
+		return fmt.Errorf("unknown host %s", j.Host)
+	}
+	switch {
+	case j.RunAsUser != "" || j.RunAsGroup != "":
+		return fmt.Errorf("jobs on remote hosts cannot run as another user")
+	case j.Isolation != "" && j.Isolation != models.IsolationNone:
+		return fmt.Errorf("jobs on remote hosts cannot use isolation %s", j.Isolation)
+	case j.Limits != nil:
+		return fmt.Errorf("jobs on remote hosts cannot declare resource limits")
+	case len(j.ArtifactGlobs) > 0:
+		return fmt.Errorf("jobs on remote hosts cannot declare artifacts")
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package job
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+	"github.com/chrlesur/orchestrator/internal/sandbox"
+)
+
+// localExecutor lance le job dans un processus de la machine de l'orchestrateur, avec son
+// identité, son isolation et ses limites de ressources
+type localExecutor struct {
+	runner *Runner
+}
+
+// localProcess est le processus local d'une tentative
+type localProcess struct {
+	cmd     *exec.Cmd
+	limits  limiter
+	cleanup func()
+}
+
+func (e *localExecutor) Start(j *models.Job, stdout, stderr io.Writer) (Process, error) {
+	r := e.runner
+
+	command, args, cleanup, err := commandLine(j)
+	if err != nil {
+		return nil, err
+	}
+	started := false
+	defer func() {
+		if !started {
+			cleanup()
+		}
+	}()
+
+	// Le processus est placé dans son propre groupe pour pouvoir tuer toute sa descendance
+	cmd := exec.Command(command, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Dir = j.WorkingDir
+	if j.Stdin != "" {
+		cmd.Stdin = strings.NewReader(j.Stdin)
+	}
+	env, err := r.environment(j)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Env = env
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	credential, err := resolveCredential(j.RunAsUser, j.RunAsGroup)
+	if err != nil {
+		return nil, err
+	}
+	if credential == nil && j.Isolation == models.IsolationSandbox {
+		credential = sandboxCredential()
+	}
+	if credential != nil {
+		cmd.SysProcAttr.Credential = credential
+		// Le script et le répertoire de travail du job doivent être accessibles à son identité
+		if err := r.grantAccess(j, args, credential); err != nil {
+			return nil, err
+		}
+	}
+	if j.Isolation == models.IsolationSandbox {
+		if err := r.isolate(j, cmd, args, credential); err != nil {
+			return nil, err
+		}
+	}
+
+	// Les limites enveloppent la commande, bac à sable compris : le processus relais les
+	// applique avant d'exécuter quoi que ce soit
+	limits, err := r.applyLimits(j, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("could not apply resource limits: %v", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		limits.release()
+		if j.Isolation == models.IsolationSandbox {
+			err = sandbox.StartError(err)
+		}
+		return nil, fmt.Errorf("command execution failed: %v", err)
+	}
+	started = true
+
+	p := &localProcess{cmd: cmd, limits: limits, cleanup: cleanup}
+	if err := limits.start(cmd.Process.Pid); err != nil {
+		p.Cancel()
+		p.cmd.Wait()
+		limits.release()
+		cleanup()
+		return nil, fmt.Errorf("could not apply resource limits: %v", err)
+	}
+	return p, nil
+}
+
+func (p *localProcess) Wait() (ExitStatus, error) {
+	defer p.cleanup()
+	defer p.limits.release()
+
+	status, err := waitCommand(p.cmd)
+	status.FailureReason = p.limits.failureReason(status)
+	return status, err
+}
+
+// Terminate envoie SIGTERM au processus du job et à tous ses descendants. Dans un bac à sable,
+// le signal est relayé à la commande par le processus init de son espace de noms
+func (p *localProcess) Terminate() {
+	signalProcessGroup(p.cmd, syscall.SIGTERM)
+}
+
+// Cancel tue le processus du job ainsi que tous ses descendants
+func (p *localProcess) Cancel() {
+	killProcessGroup(p.cmd)
+}
+
+// waitCommand attend la fin d'une commande et retourne son code de sortie et son signal.
+// Seules les erreurs autres qu'une sortie en échec sont retournées
+func waitCommand(cmd *exec.Cmd) (ExitStatus, error) {
+	err := cmd.Wait()
+	status := ExitStatus{Code: -1}
+	if state := cmd.ProcessState; state != nil {
+		status.Code = state.ExitCode()
+		if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			status.Signal = ws.Signal().String()
+		}
+	}
+	if _, exited := err.(*exec.ExitError); exited {
+		err = nil
+	}
+	return status, err
+}
@@ -0,0 +1,73 @@
+package job
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+	"github.com/chrlesur/orchestrator/internal/plugin"
+)
+
+// pluginExecutor exécute un job en appelant un plugin chargé. Le résultat du plugin est écrit
+// sur stdout ; une erreur est écrite sur stderr et termine la tentative avec le code 1
+type pluginExecutor struct {
+	plugins *plugin.PluginManager
+}
+
+// pluginProcess est l'appel d'un plugin pour une tentative
+type pluginProcess struct {
+	stdout, stderr io.Writer
+	done           chan pluginResult
+	cancelled      chan struct{}
+	cancel         sync.Once
+}
+
+type pluginResult struct {
+	value interface{}
+	err   error
+}
+
+func (e *pluginExecutor) Start(j *models.Job, stdout, stderr io.Writer) (Process, error) {
+	args := make(map[string]interface{})
+	for i, arg := range jobArgs(j) {
+		args[fmt.Sprintf("arg%d", i)] = arg
+	}
+
+	p := &pluginProcess{
+		stdout:    stdout,
+		stderr:    stderr,
+		done:      make(chan pluginResult, 1),
+		cancelled: make(chan struct{}),
+	}
+	name := j.PluginName
+	go func() {
+		value, err := e.plugins.ExecutePlugin(name, args)
+		p.done <- pluginResult{value: value, err: err}
+	}()
+	return p, nil
+}
+
+// Wait écrit la sortie du plugin une fois celui-ci terminé, jamais après une annulation
+func (p *pluginProcess) Wait() (ExitStatus, error) {
+	select {
+	case result := <-p.done:
+		if result.err != nil {
+			fmt.Fprint(p.stderr, result.err.Error())
+			return ExitStatus{Code: 1}, nil
+		}
+		_, err := fmt.Fprintf(p.stdout, "%v", result.value)
+		return ExitStatus{Code: 0}, err
+	case <-p.cancelled:
+		return ExitStatus{Code: -1}, fmt.Errorf("plugin execution interrupted")
+	}
+}
+
+// Terminate ne fait rien : le plugin peut encore terminer pendant le délai de grâce
+func (p *pluginProcess) Terminate() {}
+
+// Cancel n'interrompt pas le plugin, qui n'offre pas de moyen de l'arrêter : la tentative
+// est abandonnée et le résultat du plugin sera ignoré
+func (p *pluginProcess) Cancel() {
+	p.cancel.Do(func() { close(p.cancelled) })
+}
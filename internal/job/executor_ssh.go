@@ -0,0 +1,271 @@
+package job
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+	"github.com/chrlesur/orchestrator/pkg/logger"
+	"github.com/chrlesur/orchestrator/pkg/utils"
+)
+
+const (
+	defaultSSHCommand = "ssh"
+	// sshCancelTimeout borne la connexion qui signale la session distante d'un job interrompu
+	sshCancelTimeout = 10 * time.Second
+)
+
+// SSHHost décrit un hôte de l'inventaire sur lequel des jobs peuvent être exécutés
+type SSHHost struct {
+	Address      string // Adresse ou nom DNS, le nom de l'hôte dans l'inventaire par défaut
+	Port         int
+	User         string
+	IdentityFile string
+	Options      []string // Options -o transmises à ssh, par exemple StrictHostKeyChecking=yes
+}
+
+// sshExecutor exécute un job sur un hôte de l'inventaire avec le client ssh du système.
+// La commande est interprétée par le shell de l'utilisateur distant, qui doit être compatible sh
+type sshExecutor struct {
+	command string
+	name    string
+	host    SSHHost
+}
+
+// sshProcess est la connexion ssh d'une tentative
+type sshProcess struct {
+	executor *sshExecutor
+	cmd      *exec.Cmd
+	marker   string
+	exit     *exitReporter
+	stopped  int32 // Une fin de connexion demandée n'est pas un échec de ssh
+}
+
+func (e *sshExecutor) Start(j *models.Job, stdout, stderr io.Writer) (Process, error) {
+	// La partie aléatoire empêche la sortie d'un job de reproduire le marqueur par accident
+	marker := fmt.Sprintf("orchestrator-%s-%d-%s", j.ID, j.Attempt, utils.GenerateID(16))
+	exit := newExitReporter(stderr, exitTag(marker))
+	cmd := exec.Command(e.binary(), e.args(remoteCommand(j, marker))...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if j.Stdin != "" {
+		cmd.Stdin = strings.NewReader(j.Stdin)
+	}
+	cmd.Stdout = stdout
+	cmd.Stderr = exit
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("could not start ssh: %v", err)
+	}
+	return &sshProcess{executor: e, cmd: cmd, marker: marker, exit: exit}, nil
+}
+
+func (e *sshExecutor) binary() string {
+	if e.command == "" {
+		return defaultSSHCommand
+	}
+	return e.command
+}
+
+// args construit les arguments de ssh pour exécuter une commande sur l'hôte. Le mode batch
+// interdit toute demande interactive de mot de passe ou de confirmation
+func (e *sshExecutor) args(remote string) []string {
+	args := []string{"-T", "-o", "BatchMode=yes"}
+	if e.host.Port > 0 {
+		args = append(args, "-p", strconv.Itoa(e.host.Port))
+	}
+	if e.host.User != "" {
+		args = append(args, "-l", e.host.User)
+	}
+	if e.host.IdentityFile != "" {
+		args = append(args, "-i", e.host.IdentityFile)
+	}
+	for _, option := range e.host.Options {
+		args = append(args, "-o", option)
+	}
+
+	address := e.host.Address
+	if address == "" {
+		address = e.name
+	}
+	return append(args, "--", address, remote)
+}
+
+// Wait attend la fin de la connexion. Le code de sortie est celui rapporté par le shell distant :
+// sans lui, la connexion a échoué ou s'est interrompue avant la fin de la commande
+func (p *sshProcess) Wait() (ExitStatus, error) {
+	status, err := waitCommand(p.cmd)
+	p.exit.flush()
+	if code, ok := p.exit.code(); ok {
+		status.Code = code
+	} else if err == nil && atomic.LoadInt32(&p.stopped) == 0 {
+		err = fmt.Errorf("could not run the job on host %s (ssh exit code %d)", p.executor.name, status.Code)
+	}
+	return status, err
+}
+
+// Terminate envoie SIGTERM à la session distante du job
+func (p *sshProcess) Terminate() {
+	atomic.StoreInt32(&p.stopped, 1)
+	p.signalSession("TERM")
+}
+
+// Cancel tue la session distante du job puis la connexion ssh. Fermer la connexion ne suffit
+// pas : sans terminal, les processus distants ne reçoivent aucun signal
+func (p *sshProcess) Cancel() {
+	atomic.StoreInt32(&p.stopped, 1)
+	p.signalSession("KILL")
+	killProcessGroup(p.cmd)
+}
+
+// signalSession envoie un signal aux processus de la session distante du job
+func (p *sshProcess) signalSession(sig string) {
+	ctx, cancel := context.WithTimeout(context.Background(), sshCancelTimeout)
+	defer cancel()
+	// Les sessions dont le meneur porte le marqueur reçoivent le signal. Le crochet empêche le
+	// motif de correspondre à la commande qui le recherche
+	pattern := "[" + p.marker[:1] + "]" + regexp.QuoteMeta(p.marker[1:])
+	command := `command -v pgrep >/dev/null && command -v pkill >/dev/null || ` +
+		`{ echo "pgrep and pkill are required to signal the job" >&2; exit 127; }; ` +
+		fmt.Sprintf(`for sid in $(pgrep -f %s); do pkill -%s -s "$sid"; done`, shellQuote(pattern), sig)
+	if out, err := exec.CommandContext(ctx, p.executor.binary(), p.executor.args(command)...).CombinedOutput(); err != nil {
+		logger.Warning(fmt.Sprintf("Could not signal remote session %s on host %s: %v %s", p.marker, p.executor.name, err, strings.TrimSpace(string(out))))
+	}
+}
+
+// remoteCommand construit la ligne interprétée par le shell distant : répertoire de travail,
+// variables du job puis commande ou script. Le shell reste le parent de la commande et le
+// commentaire final, qui figure dans sa ligne de commande, permet de retrouver sa session.
+// Le shell intercepte SIGTERM pour survivre à Terminate, la commande recevant le signal par
+// défaut, puis rapporte son code de sortie sur stderr
+func remoteCommand(j *models.Job, marker string) string {
+	var b strings.Builder
+	b.WriteString("trap : TERM; ")
+	if j.WorkingDir != "" {
+		b.WriteString("cd " + shellQuote(j.WorkingDir) + " && ")
+	}
+
+	env := "env"
+	for _, kv := range jobEnvironment(j) {
+		env += " " + shellQuote(kv)
+	}
+	args := ""
+	for _, arg := range jobArgs(j) {
+		args += " " + shellQuote(arg)
+	}
+
+	if IsScript(j) {
+		// Le corps du script est écrit dans un fichier temporaire de l'hôte distant
+		b.WriteString(`f=$(mktemp) && printf '%s' ` + shellQuote(j.Script) + ` > "$f" && `)
+		b.WriteString(env + " " + shellQuote(interpreter(j)) + ` "$f"` + args)
+		b.WriteString(`; rc=$?; rm -f "$f"`)
+	} else {
+		b.WriteString(env + " " + shellQuote(j.Command) + args + "; rc=$?")
+	}
+
+	b.WriteString(`; printf '%s%d\n' ` + shellQuote(exitTag(marker)) + ` "$rc" >&2; exit $rc`)
+	b.WriteString(" # " + marker)
+	return b.String()
+}
+
+// shellQuote protège une valeur pour le shell distant
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// exitTag précède le code de sortie rapporté par le shell distant
+func exitTag(marker string) string {
+	return marker + " exit "
+}
+
+// exitReporter retire de stderr la ligne par laquelle le shell distant rapporte le code de
+// sortie de la commande et transmet le reste du flux, y compris ce qu'écrivent après elle
+// les processus laissés en arrière-plan
+type exitReporter struct {
+	out     io.Writer
+	tag     []byte
+	pending []byte // Fin du flux qui peut être le début du marqueur
+	found   bool
+	status  []byte // Ligne du code de sortie, jusqu'à son retour à la ligne
+	done    bool   // La ligne du code de sortie est complète
+}
+
+func newExitReporter(out io.Writer, tag string) *exitReporter {
+	return &exitReporter{out: out, tag: []byte(tag)}
+}
+
+func (r *exitReporter) Write(p []byte) (int, error) {
+	if r.done {
+		return len(p), r.forward(p)
+	}
+	if r.found {
+		return len(p), r.readStatus(p)
+	}
+	data := append(r.pending, p...)
+	if i := bytes.Index(data, r.tag); i >= 0 {
+		r.found = true
+		r.pending = nil
+		if err := r.forward(data[:i]); err != nil {
+			return len(p), err
+		}
+		return len(p), r.readStatus(data[i+len(r.tag):])
+	}
+	keep := partialSuffix(data, r.tag)
+	r.pending = append([]byte(nil), data[len(data)-keep:]...)
+	return len(p), r.forward(data[:len(data)-keep])
+}
+
+// readStatus complète la ligne du code de sortie et transmet ce qui la suit
+func (r *exitReporter) readStatus(data []byte) error {
+	i := bytes.IndexByte(data, '\n')
+	if i < 0 {
+		r.status = append(r.status, data...)
+		return nil
+	}
+	r.status = append(r.status, data[:i]...)
+	r.done = true
+	return r.forward(data[i+1:])
+}
+
+func (r *exitReporter) forward(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	_, err := r.out.Write(data)
+	return err
+}
+
+// flush transmet la fin du flux retenue lorsqu'elle n'était finalement pas le marqueur
+func (r *exitReporter) flush() {
+	if !r.found {
+		r.forward(r.pending)
+		r.pending = nil
+	}
+}
+
+// code retourne le code de sortie rapporté, s'il a été reçu
+func (r *exitReporter) code() (int, bool) {
+	if !r.found {
+		return 0, false
+	}
+	code, err := strconv.Atoi(strings.TrimSpace(string(r.status)))
+	return code, err == nil
+}
+
+// partialSuffix retourne la longueur de la plus longue fin de data qui commence tag
+func partialSuffix(data, tag []byte) int {
+	for n := len(tag) - 1; n > 0; n-- {
+		if len(data) >= n && bytes.Equal(data[len(data)-n:], tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,353 @@
+package job
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sshTestServer est un serveur SSH minimal : chaque commande reçue est exécutée localement
+// par sh dans sa propre session, comme le fait sshd sans terminal, et son code de sortie est
+// rapporté par la requête exit-status ou exit-signal
+type sshTestServer struct {
+	listener net.Listener
+	config   *ssh.ServerConfig
+	wg       sync.WaitGroup
+}
+
+func newSSHTestServer(t *testing.T, hostKey ssh.Signer, authorized ssh.PublicKey) *sshTestServer {
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if !bytes.Equal(key.Marshal(), authorized.Marshal()) {
+				return nil, &ssh.PartialSuccessError{}
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &sshTestServer{listener: listener, config: config}
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(func() {
+		listener.Close()
+		s.wg.Wait()
+	})
+	return s
+}
+
+func (s *sshTestServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *sshTestServer) handle(conn net.Conn) {
+	defer conn.Close()
+	_, channels, requests, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go runSession(channel, requests)
+	}
+}
+
+func runSession(channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+	for req := range requests {
+		if req.Type != "exec" {
+			req.Reply(false, nil)
+			continue
+		}
+		var payload struct{ Command string }
+		if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+			req.Reply(false, nil)
+			return
+		}
+		req.Reply(true, nil)
+
+		cmd := exec.Command("sh", "-c", payload.Command)
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+		cmd.Stdin = channel
+		cmd.Stdout = channel
+		cmd.Stderr = channel.Stderr()
+		cmd.Run()
+
+		status := cmd.ProcessState.Sys().(syscall.WaitStatus)
+		if status.Signaled() {
+			channel.SendRequest("exit-signal", false, ssh.Marshal(struct {
+				Signal     string
+				CoreDumped bool
+				Error      string
+				Lang       string
+			}{Signal: sshSignals[status.Signal()]}))
+		} else {
+			channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(status.ExitStatus())}))
+		}
+		return
+	}
+}
+
+// Noms des signaux dans le protocole SSH
+var sshSignals = map[syscall.Signal]string{
+	syscall.SIGHUP: "HUP", syscall.SIGINT: "INT", syscall.SIGKILL: "KILL", syscall.SIGTERM: "TERM",
+}
+
+// lockedBuffer est un bytes.Buffer lisible pendant que la tentative y écrit
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// sshTestHost démarre un serveur et retourne un exécuteur qui s'y connecte avec le client ssh du
+// système, en vérifiant la clé d'hôte inscrite dans knownHost (celle du serveur si nil)
+func sshTestHost(t *testing.T, knownHost ssh.PublicKey, clientKey ed25519.PrivateKey) *sshExecutor {
+	if _, err := exec.LookPath("ssh"); err != nil {
+		t.Skip("the ssh client is not installed")
+	}
+	dir := t.TempDir()
+
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostKey, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, authorizedPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorized, err := ssh.NewPublicKey(authorizedPriv.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientKey == nil {
+		clientKey = authorizedPriv
+	}
+	if knownHost == nil {
+		knownHost = hostKey.PublicKey()
+	}
+	server := newSSHTestServer(t, hostKey, authorized)
+
+	block, err := ssh.MarshalPrivateKey(clientKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity := filepath.Join(dir, "id_ed25519")
+	if err := ioutil.WriteFile(identity, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	addr := server.listener.Addr().String()
+	knownHosts := filepath.Join(dir, "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(addr)}, knownHost)
+	if err := ioutil.WriteFile(knownHosts, []byte(line+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := user.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, port, _ := net.SplitHostPort(addr)
+	portNumber, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &sshExecutor{name: "test", host: SSHHost{
+		Address:      host,
+		Port:         portNumber,
+		User:         current.Username,
+		IdentityFile: identity,
+		Options: []string{
+			"StrictHostKeyChecking=yes",
+			"UserKnownHostsFile=" + knownHosts,
+			"GlobalKnownHostsFile=/dev/null",
+			"IdentitiesOnly=yes",
+			"LogLevel=ERROR",
+		},
+	}}
+}
+
+// waitProcess attend la fin d'une tentative, en la tuant si elle ne se termine pas
+func waitProcess(t *testing.T, p Process) (ExitStatus, error) {
+	t.Helper()
+	type result struct {
+		status ExitStatus
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		status, err := p.Wait()
+		done <- result{status, err}
+	}()
+	select {
+	case r := <-done:
+		return r.status, r.err
+	case <-time.After(15 * time.Second):
+		p.Cancel()
+		t.Fatal("the remote job did not finish")
+	}
+	return ExitStatus{}, nil
+}
+
+func TestSSHServerExitCodes(t *testing.T) {
+	e := sshTestHost(t, nil, nil)
+	tests := []struct {
+		name   string
+		script string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{"success", `echo out; echo err >&2`, 0, "out\n", "err\n"},
+		{"failure", `exit 3`, 3, "", ""},
+		{"remote 255", `exit 255`, 255, "", ""},
+		{"tag without nonce", `echo "orchestrator-ssh-1 exit 7" >&2`, 0, "", "orchestrator-ssh-1 exit 7\n"},
+		{"output after the exit code", `(sleep 0.3; echo late >&2) & exit 4`, 4, "", "late\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			p, err := e.Start(sshTestJob(tt.script), &stdout, &stderr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			status, err := waitProcess(t, p)
+			if err != nil {
+				t.Fatalf("unexpected error: %v (stderr %q)", err, stderr.String())
+			}
+			if status.Code != tt.code {
+				t.Fatalf("exit code = %d, want %d", status.Code, tt.code)
+			}
+			if stdout.String() != tt.stdout || stderr.String() != tt.stderr {
+				t.Fatalf("stdout = %q, stderr = %q, want %q and %q", stdout.String(), stderr.String(), tt.stdout, tt.stderr)
+			}
+		})
+	}
+}
+
+func TestSSHServerConnectionFailures(t *testing.T) {
+	_, otherHost, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherHostKey, err := ssh.NewPublicKey(otherHost.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherClient, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		knownHost ssh.PublicKey
+		client    ed25519.PrivateKey
+	}{
+		{"unknown host key", otherHostKey, nil},
+		{"rejected client key", nil, otherClient},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := sshTestHost(t, tt.knownHost, tt.client)
+			var stdout, stderr bytes.Buffer
+			p, err := e.Start(sshTestJob(`echo ran`), &stdout, &stderr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			status, err := waitProcess(t, p)
+			if err == nil {
+				t.Fatalf("connection failure not reported, exit code %d", status.Code)
+			}
+			if status.Code != 255 || stdout.Len() > 0 {
+				t.Fatalf("exit code = %d, stdout = %q, want 255 and no output", status.Code, stdout.String())
+			}
+		})
+	}
+}
+
+func TestSSHServerCancellation(t *testing.T) {
+	e := sshTestHost(t, nil, nil)
+	tests := []struct {
+		name   string
+		script string
+		stop   func(Process)
+		code   int
+	}{
+		{"terminate", `trap 'echo stopping; exit 3' TERM; echo started; sleep 30 & wait`, Process.Terminate, 3},
+		{"cancel", `echo started; sleep 30`, Process.Cancel, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr lockedBuffer
+			p, err := e.Start(sshTestJob(tt.script), &stdout, &stderr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			marker := p.(*sshProcess).marker
+			waitFor(t, func() bool { return strings.HasPrefix(stdout.String(), "started") })
+			tt.stop(p)
+
+			status, err := waitProcess(t, p)
+			if err != nil {
+				t.Fatalf("stopping the job reported an error: %v", err)
+			}
+			if tt.code >= 0 && status.Code != tt.code {
+				t.Fatalf("exit code = %d, want %d (stderr %q)", status.Code, tt.code, stderr.String())
+			}
+			// Aucun processus de la session distante ne doit survivre
+			waitFor(t, func() bool {
+				return exec.Command("pgrep", "-f", marker).Run() != nil
+			})
+		})
+	}
+}
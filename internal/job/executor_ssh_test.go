@@ -0,0 +1,114 @@
+package job
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+)
+
+// fakeSSH remplace le client ssh : la commande distante est exécutée localement, dans sa
+// propre session comme le ferait sshd
+const fakeSSH = `#!/bin/sh
+while [ "$1" != "--" ]; do shift; done
+shift 2
+exec setsid -w sh -c "$1"
+`
+
+// unreachableSSH simule un hôte injoignable
+const unreachableSSH = `#!/bin/sh
+echo "ssh: connect to host fake port 22: Connection refused" >&2
+exit 255
+`
+
+func sshTestExecutor(t *testing.T, script string) *sshExecutor {
+	path := filepath.Join(t.TempDir(), "ssh")
+	if err := ioutil.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return &sshExecutor{command: path, name: "fake"}
+}
+
+func sshTestJob(args ...string) *models.Job {
+	return &models.Job{ID: "ssh", Attempt: 1, Command: "sh", Args: append([]string{"-c"}, args...)}
+}
+
+func TestSSHExecutorReportsRemoteExitCode(t *testing.T) {
+	e := sshTestExecutor(t, fakeSSH)
+	var stdout, stderr bytes.Buffer
+	p, err := e.Start(sshTestJob(`echo out; printf err >&2; exit 255`), &stdout, &stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := p.Wait()
+	if err != nil {
+		t.Fatalf("remote exit code 255 reported as a connection error: %v", err)
+	}
+	if status.Code != 255 {
+		t.Fatalf("exit code = %d, want 255", status.Code)
+	}
+	if stdout.String() != "out\n" || stderr.String() != "err" {
+		t.Fatalf("stdout = %q, stderr = %q", stdout.String(), stderr.String())
+	}
+}
+
+func TestSSHExecutorDetectsConnectionFailure(t *testing.T) {
+	e := sshTestExecutor(t, unreachableSSH)
+	var stdout, stderr bytes.Buffer
+	p, err := e.Start(sshTestJob(`true`), &stdout, &stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Wait(); err == nil {
+		t.Fatal("connection failure was not reported")
+	}
+}
+
+func TestSSHExecutorTerminatesRemoteSession(t *testing.T) {
+	e := sshTestExecutor(t, fakeSSH)
+	var stdout, stderr bytes.Buffer
+	p, err := e.Start(sshTestJob(`trap 'echo stopping; exit 3' TERM; sleep 30 & wait`), &stdout, &stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Laisse au shell distant le temps d'installer son gestionnaire
+	time.Sleep(300 * time.Millisecond)
+	p.Terminate()
+
+	done := make(chan struct{})
+	var status ExitStatus
+	go func() {
+		defer close(done)
+		status, err = p.Wait()
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		p.Cancel()
+		t.Fatal("remote session was not terminated")
+	}
+	if err != nil || status.Code != 3 {
+		t.Fatalf("status = %+v, err = %v, want exit code 3", status, err)
+	}
+	if stdout.String() != "stopping\n" {
+		t.Fatalf("stdout = %q, stderr = %q", stdout.String(), stderr.String())
+	}
+}
+
+func TestExitReporterSplitTag(t *testing.T) {
+	var out bytes.Buffer
+	r := newExitReporter(&out, exitTag("orchestrator-x-1"))
+	for _, chunk := range []string{"partial orch", "estrator-x-1 ex", "it 42", "\nlate", " output\n"} {
+		r.Write([]byte(chunk))
+	}
+	r.flush()
+	if code, ok := r.code(); !ok || code != 42 {
+		t.Fatalf("code = %d, %v, want 42", code, ok)
+	}
+	if want := "partial late output\n"; out.String() != want {
+		t.Fatalf("forwarded %q, want %q", out.String(), want)
+	}
+}
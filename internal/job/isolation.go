@@ -0,0 +1,75 @@
+package job
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+	"github.com/chrlesur/orchestrator/internal/sandbox"
+)
+
+// ValidateIsolation vérifie le mode d'isolation d'un job et sa disponibilité sur la machine
+func ValidateIsolation(isolation models.Isolation) error {
+	switch isolation {
+	case "", models.IsolationNone:
+		return nil
+	case models.IsolationSandbox:
+		return sandbox.Available()
+	}
+	return fmt.Errorf("unknown isolation: %s", isolation)
+}
+
+// nobodyID est l'identité de l'hôte d'un job isolé sans run_as_user lorsque l'orchestrateur est root
+const nobodyID = 65534
+
+// sandboxCredential retourne l'identité d'un job isolé qui n'en demande pas : nobody lorsque
+// l'orchestrateur est root, sinon son identité, la seule qu'il puisse faire correspondre
+func sandboxCredential() *syscall.Credential {
+	if os.Geteuid() != 0 {
+		return nil
+	}
+	return &syscall.Credential{Uid: nobodyID, Gid: nobodyID}
+}
+
+// isolate place la commande d'un job dans un bac à sable. Seuls les chemins configurés, le
+// script du job et son répertoire de travail partagé, accessible en écriture, y sont visibles
+func (r *Runner) isolate(j *models.Job, cmd *exec.Cmd, args []string, cred *syscall.Credential) error {
+	paths := r.settings.SandboxPaths
+	if len(paths) == 0 {
+		paths = sandbox.DefaultPaths
+	}
+	spec := sandbox.Spec{
+		ReadOnly:   append([]string(nil), paths...),
+		WorkingDir: j.WorkingDir,
+		Hostname:   j.ID,
+		UID:        os.Getuid(),
+		GID:        os.Getgid(),
+	}
+	if IsScript(j) && len(args) > 0 {
+		spec.ReadOnly = append(spec.ReadOnly, args[0])
+	}
+	if r.settings.WorkspaceDir != "" {
+		workspace, err := WorkspacePath(r.settings.WorkspaceDir, j)
+		if err == nil {
+			workspace, err = filepath.Abs(workspace)
+		}
+		if err != nil {
+			return err
+		}
+		spec.Writable = []string{workspace}
+		if spec.WorkingDir == "" {
+			spec.WorkingDir = workspace
+		}
+	}
+	if cred != nil {
+		spec.UID, spec.GID = int(cred.Uid), int(cred.Gid)
+	}
+
+	if err := sandbox.Wrap(cmd, spec); err != nil {
+		return fmt.Errorf("could not isolate job: %v", err)
+	}
+	return nil
+}
@@ -3,13 +3,52 @@ package job
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"syscall"
 	"time"
 
+	"github.com/chrlesur/orchestrator/internal/db"
 	"github.com/chrlesur/orchestrator/internal/models"
+	"github.com/chrlesur/orchestrator/internal/plugin"
 	"github.com/chrlesur/orchestrator/pkg/logger"
+	"github.com/chrlesur/orchestrator/pkg/utils"
 )
 
+// Settings regroupe les paramètres d'exécution des jobs issus de la configuration
+type Settings struct {
+	DefaultTimeout time.Duration
+	GracePeriod    time.Duration // Délai par défaut entre SIGTERM et SIGKILL
+	MaxRetries     int
+	RetryPolicy    models.RetryPolicy // Politique appliquée aux jobs qui n'en définissent pas
+	LogDir         string
+	WorkspaceDir   string   // Racine des répertoires partagés exposés via ORCH_WORKSPACE
+	CgroupRoot     string   // Cgroup v2 parent des cgroups créés pour les jobs limités
+	MaxOutputSize  int      // Taille maximale de stdout conservée sur le job (0 : illimitée)
+	OutputDir      string   // Répertoire des sorties complètes ayant dépassé MaxOutputSize
+	ArtifactDir    string   // Magasin des artefacts déclarés par les jobs (vide : désactivé)
+	SandboxPaths   []string // Chemins de l'hôte visibles en lecture seule par les jobs isolés
+	RunAsUsers     []string // Utilisateurs sous lesquels un job peut demander à s'exécuter
+	RunAsGroups    []string
+	SSHCommand     string                // Client ssh utilisé pour les jobs distants, ssh par défaut
+	SSHHosts       map[string]SSHHost    // Inventaire des hôtes distants, par nom
+	RecoveryPolicy models.RecoveryPolicy // Politique de reprise des jobs qui n'en définissent pas
+}
+
+// Runner exécute les jobs en appliquant les paramètres communs de l'orchestrateur
+type Runner struct {
+	settings Settings
+	store    *db.Store
+	plugins  *plugin.PluginManager // Nécessaire aux jobs de type plugin
+}
+
+// NewRunner crée un Runner. Un LogDir vide désactive l'écriture des fichiers de log
+// et un store nil désactive l'historique des tentatives
+func NewRunner(settings Settings, store *db.Store) *Runner {
+	return &Runner{settings: settings, store: store}
+}
+
 func NewJob(id, command string, args []string, timeout time.Duration, maxRetries int) *models.Job {
 	return &models.Job{
 		ID:         id,
@@ -21,18 +60,38 @@ func NewJob(id, command string, args []string, timeout time.Duration, maxRetries
 	}
 }
 
+// Execute exécute un job avec les paramètres par défaut, sans fichiers de log
 func Execute(j *models.Job, ctx context.Context) error {
+	return NewRunner(Settings{}, nil).Execute(j, ctx)
+}
+
+func (r *Runner) Execute(j *models.Job, ctx context.Context) error {
 	j.Status = models.JobStatusRunning
 	j.StartTime = time.Now()
+	// Le passage à running est enregistré afin que la reprise au démarrage retrouve le job
+	r.saveJob(j)
 	defer func() { j.EndTime = time.Now() }()
 
+	policy := j.RetryPolicy
+	if policy == nil {
+		policy = &r.settings.RetryPolicy
+	}
+
 	for j.RetryCount <= j.MaxRetries {
-		err := run(j, ctx)
+		// Les tentatives sont numérotées à la suite de celles d'une exécution précédente
+		j.Attempt++
+		attempt, output, err := r.attempt(j, ctx)
 		if err == nil {
 			j.Status = models.JobStatusCompleted
+			r.collectArtifacts(j)
 			return nil
 		}
 
+		// Une annulation interrompt la boucle de tentatives
+		if ctx.Err() != nil {
+			return cancelled(j)
+		}
+
 		j.RetryCount++
 		j.Error = err
 		logger.Warning(fmt.Sprintf("Job %s failed (attempt %d/%d): %v", j.ID, j.RetryCount, j.MaxRetries+1, err))
@@ -40,25 +99,237 @@ func Execute(j *models.Job, ctx context.Context) error {
 		if j.RetryCount > j.MaxRetries {
 			break
 		}
+		if !shouldRetry(policy, attempt, output) {
+			logger.Info(fmt.Sprintf("Job %s will not be retried: the failure does not match its retry policy", j.ID))
+			break
+		}
 
-		// Attente exponentielle entre les tentatives
-		time.Sleep(time.Second * time.Duration(1<<uint(j.RetryCount)))
+		// Attente entre les tentatives selon la stratégie de la politique
+		select {
+		case <-ctx.Done():
+			return cancelled(j)
+		case <-time.After(retryDelay(policy, j.RetryCount)):
+		}
 	}
 
 	j.Status = models.JobStatusFailed
+	r.collectArtifacts(j)
 	return fmt.Errorf("job %s failed after %d attempts: %v", j.ID, j.RetryCount, j.Error)
 }
 
-func run(j *models.Job, ctx context.Context) error {
-	ctx, cancel := context.WithTimeout(ctx, j.Timeout)
+func cancelled(j *models.Job) error {
+	j.Status = models.JobStatusCancelled
+	j.Error = fmt.Errorf("job cancelled")
+	return fmt.Errorf("job %s cancelled", j.ID)
+}
+
+// attempt exécute une tentative, enregistre son historique et retourne sa sortie, examinée
+// par les conditions de nouvelle tentative
+func (r *Runner) attempt(j *models.Job, ctx context.Context) (*models.JobAttempt, attemptOutput, error) {
+	attempt := &models.JobAttempt{
+		JobID:     j.ID,
+		Number:    j.Attempt,
+		StartTime: time.Now(),
+		ExitCode:  -1,
+	}
+	if r.settings.LogDir != "" {
+		attempt.StdoutPath = LogPath(r.settings.LogDir, j.ID, j.Attempt, StreamStdout)
+		attempt.StderrPath = LogPath(r.settings.LogDir, j.ID, j.Attempt, StreamStderr)
+	}
+
+	j.FailureReason = ""
+	j.ForceKilled = false
+	j.Progress = 0
+	j.StatusMessage = ""
+	j.Outputs = nil
+	j.ParsedResult = nil
+	j.Result = ""
+	j.OutputTruncated = false
+	j.OutputPath = ""
+	// La tentative est enregistrée dès son démarrage, sans date de fin, puis mise à jour
+	r.saveAttempt(attempt)
+	output, err := r.run(j, ctx, attempt)
+	attempt.EndTime = time.Now()
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	r.saveAttempt(attempt)
+	return attempt, output, err
+}
+
+func (r *Runner) saveJob(j *models.Job) {
+	if r.store == nil {
+		return
+	}
+	if err := r.store.SaveJob(j); err != nil {
+		logger.Error(fmt.Sprintf("Failed to save job %s: %v", j.ID, err))
+	}
+}
+
+func (r *Runner) saveAttempt(attempt *models.JobAttempt) {
+	if r.store == nil {
+		return
+	}
+	if err := r.store.SaveAttempt(attempt); err != nil {
+		logger.Error(fmt.Sprintf("Failed to save attempt %d of job %s: %v", attempt.Number, attempt.JobID, err))
+	}
+}
+
+func (r *Runner) run(j *models.Job, ctx context.Context, attempt *models.JobAttempt) (attemptOutput, error) {
+	runCtx, cancel := context.WithTimeout(ctx, j.Timeout)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, j.Command, j.Args...)
-	output, err := cmd.CombinedOutput()
+	executor, err := r.executor(j)
+	if err != nil {
+		return attemptOutput{}, err
+	}
+
+	// stdout et stderr sont capturés séparément et recopiés dans les fichiers de la tentative.
+	// Seul un aperçu borné est gardé en mémoire ; la sortie complète de stdout reste dans le
+	// log de la tentative, ou déborde dans OutputDir lorsque les logs sont désactivés
+	overflowPath := ""
+	if r.settings.LogDir == "" && r.settings.OutputDir != "" {
+		overflowPath = OutputPath(r.settings.OutputDir, j.ID, j.Attempt)
+	}
+	stdout := newCappedOutput(r.settings.MaxOutputSize, overflowPath)
+	stderr := newCappedOutput(r.settings.MaxOutputSize, "")
+	defer stdout.Close()
+	// Les directives de progression sont lues au fil de l'eau sur stdout
+	progress := newProgressParser(j)
+	var stdoutWriter, stderrWriter io.Writer = io.MultiWriter(stdout, progress), stderr
+	if r.settings.LogDir != "" {
+		stdoutFile, stderrFile, err := openAttemptLogs(r.settings.LogDir, j.ID, j.Attempt)
+		if err != nil {
+			return attemptOutput{}, fmt.Errorf("could not open log files: %v", err)
+		}
+		defer stdoutFile.Close()
+		defer stderrFile.Close()
+		stdoutWriter = io.MultiWriter(stdout, progress, stdoutFile)
+		stderrWriter = io.MultiWriter(stderr, stderrFile)
+	}
+
+	process, err := executor.Start(j, stdoutWriter, stderrWriter)
 	if err != nil {
-		return fmt.Errorf("command execution failed: %v, output: %s", err, string(output))
+		return attemptOutput{}, err
+	}
+
+	// À l'expiration du timeout ou à l'annulation, la tentative est invitée à s'arrêter puis
+	// tuée si elle est toujours en cours à la fin de son délai de grâce
+	exited := make(chan struct{})
+	stopped := make(chan struct{})
+	forced := false
+	go func() {
+		defer close(stopped)
+		select {
+		case <-runCtx.Done():
+		case <-exited:
+			return
+		}
+		if j.GracePeriod > 0 {
+			process.Terminate()
+			select {
+			case <-exited:
+				return
+			case <-time.After(j.GracePeriod):
+			}
+			logger.Warning(fmt.Sprintf("Job %s did not stop within its grace period of %s, killing it", j.ID, j.GracePeriod))
+		}
+		forced = true
+		process.Cancel()
+	}()
+
+	status, err := process.Wait()
+	close(exited)
+	<-stopped
+	attempt.ForceKilled = forced
+	j.ForceKilled = forced
+	progress.Close()
+	if closeErr := stdout.Close(); closeErr != nil {
+		logger.Warning(fmt.Sprintf("Could not write full output of job %s: %v", j.ID, closeErr))
+	}
+	// L'aperçu est conservé quelle que soit l'issue de la tentative, sans les directives
+	j.Result = stripDirectives(stdout.String())
+	j.OutputTruncated = stdout.truncated
+	j.OutputPath = stdout.overflowPath()
+	if stdout.truncated && attempt.StdoutPath != "" {
+		j.OutputPath = attempt.StdoutPath
+	}
+	// Les critères de succès et les conditions de nouvelle tentative examinent la sortie
+	// complète, relue depuis les fichiers lorsque l'aperçu est tronqué
+	output := attemptOutput{
+		stdout: streamOutput{preview: stdout.String(), truncated: stdout.truncated, path: j.OutputPath},
+		stderr: streamOutput{preview: stderr.String(), truncated: stderr.truncated, path: attempt.StderrPath},
+	}
+	attempt.ExitCode = status.Code
+	attempt.Signal = status.Signal
+	j.ExitCode = attempt.ExitCode
+	attempt.TimedOut = runCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil
+	if attempt.TimedOut {
+		attempt.FailureReason = models.FailureTimedOut
+		j.FailureReason = attempt.FailureReason
+		if forced {
+			return output, fmt.Errorf("command timed out after %s and was killed", j.Timeout)
+		}
+		return output, fmt.Errorf("command timed out after %s", j.Timeout)
+	}
+	// Une tentative annulée n'est pas réussie, même lorsqu'elle s'est arrêtée proprement
+	if ctx.Err() != nil {
+		return output, fmt.Errorf("command interrupted: %v", ctx.Err())
 	}
 
-	j.Result = string(output)
-	return nil
+	attempt.FailureReason = status.FailureReason
+	j.FailureReason = attempt.FailureReason
+	if attempt.FailureReason != "" {
+		return output, fmt.Errorf("resource limit exceeded (%s): %v", attempt.FailureReason, status)
+	}
+
+	// Un code de sortie non nul peut être accepté par les critères de succès du job,
+	// mais pas une terminaison par signal ou une erreur d'exécution
+	if err == nil && status.Signal != "" {
+		err = fmt.Errorf("%v", status)
+	}
+	if err != nil {
+		return output, fmt.Errorf("command execution failed: %v, stderr: %s", err, utils.TruncateString(stderr.String(), 1024))
+	}
+	if err := checkSuccess(j.Success, attempt.ExitCode, output.stdout); err != nil {
+		return output, fmt.Errorf("command execution failed: %v, stderr: %s", err, utils.TruncateString(stderr.String(), 1024))
+	}
+
+	if err := parseResult(j, j.Result, stdout.truncated); err != nil {
+		return output, err
+	}
+	return output, nil
+}
+
+// openAttemptLogs crée les fichiers stdout et stderr d'une tentative
+func openAttemptLogs(dir, jobID string, attempt int) (*os.File, *os.File, error) {
+	if err := os.MkdirAll(jobLogDir(dir, jobID), 0755); err != nil {
+		return nil, nil, err
+	}
+	stdoutFile, err := os.Create(LogPath(dir, jobID, attempt, StreamStdout))
+	if err != nil {
+		return nil, nil, err
+	}
+	stderrFile, err := os.Create(LogPath(dir, jobID, attempt, StreamStderr))
+	if err != nil {
+		stdoutFile.Close()
+		return nil, nil, err
+	}
+	return stdoutFile, stderrFile, nil
+}
+
+// killProcessGroup tue le processus du job ainsi que tous ses descendants
+func killProcessGroup(cmd *exec.Cmd) {
+	signalProcessGroup(cmd, syscall.SIGKILL)
+}
+
+// signalProcessGroup envoie un signal au groupe de processus d'une commande, à défaut à
+// la commande elle-même
+func signalProcessGroup(cmd *exec.Cmd, sig syscall.Signal) {
+	if cmd.Process == nil {
+		return
+	}
+	if err := syscall.Kill(-cmd.Process.Pid, sig); err != nil {
+		cmd.Process.Signal(sig)
+	}
 }
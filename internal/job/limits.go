@@ -0,0 +1,37 @@
+package job
+
+import (
+	"fmt"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+)
+
+// ValidateLimits vérifie les limites de ressources déclarées par un job
+func ValidateLimits(l *models.ResourceLimits) error {
+	if l == nil {
+		return nil
+	}
+	if l.MemoryBytes < 0 || l.CPUs < 0 || l.MaxProcesses < 0 || l.MaxOpenFiles < 0 {
+		return fmt.Errorf("resource limits must not be negative")
+	}
+	return nil
+}
+
+// limiter suit les limites appliquées au processus d'une tentative
+type limiter interface {
+	// start place le processus démarré sous ses limites puis le laisse exécuter la commande
+	// du job, qu'il n'exécute pas avant
+	start(pid int) error
+	// failureReason indique, une fois le processus terminé, si son échec est dû au
+	// dépassement d'une limite
+	failureReason(status ExitStatus) string
+	// release libère les ressources système associées aux limites
+	release()
+}
+
+// noLimiter est utilisé pour les jobs sans limite de ressources
+type noLimiter struct{}
+
+func (noLimiter) start(int) error                 { return nil }
+func (noLimiter) failureReason(ExitStatus) string { return "" }
+func (noLimiter) release()                        {}
@@ -0,0 +1,324 @@
+package job
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+	"github.com/chrlesur/orchestrator/pkg/logger"
+)
+
+const (
+	cgroup2SuperMagic = 0x63677270
+	cgroupCPUPeriod   = 100000
+	// cgroupDrainTimeout borne l'attente de la fin des processus d'un cgroup avant sa suppression
+	cgroupDrainTimeout = 5 * time.Second
+
+	// limitsInitName est le nom sous lequel l'orchestrateur se relance pour placer un job sous
+	// ses limites avant d'exécuter sa commande
+	limitsInitName = "orchestrator-limits-init"
+	// limitsSpecEnv transmet les limites au processus relancé
+	limitsSpecEnv = "ORCH_LIMITS_SPEC"
+	// limitsInitFailure est le code de sortie du processus relais lorsqu'il ne peut pas
+	// exécuter la commande
+	limitsInitFailure = 126
+)
+
+// limitsSpec décrit au processus relais les limites à appliquer
+type limitsSpec struct {
+	Rlimits []rlimit
+	ReadyFD int // Descripteur sur lequel l'orchestrateur autorise l'exécution de la commande
+}
+
+type rlimit struct {
+	Resource int
+	Cur      uint64
+	Max      uint64
+}
+
+// applyLimits fait démarrer le job par un processus relais qui fixe ses rlimits puis attend,
+// avant d'exécuter la commande, que l'orchestrateur l'ait placé dans son cgroup. Un cgroup v2
+// est utilisé lorsqu'il est disponible, sinon toutes les limites passent par setrlimit
+func (r *Runner) applyLimits(j *models.Job, cmd *exec.Cmd) (limiter, error) {
+	l := j.Limits
+	if l == nil {
+		return noLimiter{}, nil
+	}
+
+	var limits []rlimit
+	// Le nombre de fichiers ouverts ne relève pas des cgroups
+	if l.MaxOpenFiles > 0 {
+		n := uint64(l.MaxOpenFiles)
+		limits = append(limits, rlimit{Resource: syscall.RLIMIT_NOFILE, Cur: n, Max: n})
+	}
+
+	p := &processLimiter{}
+	if r.settings.CgroupRoot != "" && cgroupV2Available(filepath.Dir(r.settings.CgroupRoot)) {
+		cg, err := newCgroup(r.settings.CgroupRoot, fmt.Sprintf("%s-%d", j.ID, j.Attempt), l)
+		if err == nil {
+			p.cgroup = cg
+		} else {
+			logger.Warning(fmt.Sprintf("Could not use cgroup v2 for job %s, falling back to rlimits: %v", j.ID, err))
+		}
+	}
+	if p.cgroup == nil {
+		// RLIMIT_NPROC compte tous les processus de l'utilisateur, pas ceux du job : la limite
+		// dépendrait des autres jobs et sessions de la même identité
+		if l.MaxProcesses > 0 {
+			return nil, fmt.Errorf("max_processes requires cgroup v2 under jobs.cgroup_root")
+		}
+		limits = append(limits, fallbackRlimits(j)...)
+	}
+
+	var err error
+	p.child, p.ready, err = os.Pipe()
+	if err != nil {
+		p.release()
+		return nil, err
+	}
+	data, err := json.Marshal(limitsSpec{Rlimits: limits, ReadyFD: 3 + len(cmd.ExtraFiles)})
+	if err != nil {
+		p.release()
+		return nil, err
+	}
+	cmd.ExtraFiles = append(cmd.ExtraFiles, p.child)
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd.Env = append(env, limitsSpecEnv+"="+string(data))
+	cmd.Args = append([]string{limitsInitName, cmd.Path}, cmd.Args...)
+	cmd.Path = "/proc/self/exe"
+	return p, nil
+}
+
+// fallbackRlimits approche sans cgroup les limites de mémoire et de CPU. Un dépassement de
+// RLIMIT_AS fait seulement échouer les allocations du job : il n'est pas reconnu comme tel
+func fallbackRlimits(j *models.Job) []rlimit {
+	l := j.Limits
+	var limits []rlimit
+	if l.MemoryBytes > 0 {
+		n := uint64(l.MemoryBytes)
+		limits = append(limits, rlimit{Resource: syscall.RLIMIT_AS, Cur: n, Max: n})
+	}
+	if l.CPUs > 0 && j.Timeout > 0 {
+		// Le débit de CPU devient un budget de temps CPU sur la durée maximale du job : le
+		// noyau envoie SIGXCPU lorsqu'il est épuisé, puis SIGKILL une seconde plus tard
+		budget := uint64(math.Ceil(l.CPUs * j.Timeout.Seconds()))
+		limits = append(limits, rlimit{Resource: syscall.RLIMIT_CPU, Cur: budget, Max: budget + 1})
+	} else if l.CPUs > 0 {
+		logger.Warning(fmt.Sprintf("CPU limit of job %s requires cgroup v2 or a timeout and is not enforced", j.ID))
+	}
+	return limits
+}
+
+// InitLimits applique les limites transmises puis exécute la commande du job lorsque le
+// processus courant est un relais lancé par applyLimits. Dans tous les autres cas, la fonction
+// ne fait rien. Elle doit être appelée au tout début de main, les processus relais étant des
+// exécutions de l'orchestrateur lui-même
+func InitLimits() {
+	if len(os.Args) < 3 || os.Args[0] != limitsInitName {
+		return
+	}
+	if err := execLimited(os.Args[1], os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "limits: %v\n", err)
+		os.Exit(limitsInitFailure)
+	}
+}
+
+func execLimited(path string, argv []string) error {
+	var spec limitsSpec
+	if err := json.Unmarshal([]byte(os.Getenv(limitsSpecEnv)), &spec); err != nil {
+		return fmt.Errorf("invalid limits description: %v", err)
+	}
+	os.Unsetenv(limitsSpecEnv)
+
+	// La commande n'est exécutée qu'une fois le processus placé dans son cgroup
+	ready := os.NewFile(uintptr(spec.ReadyFD), "ready")
+	buf := make([]byte, 1)
+	if n, _ := ready.Read(buf); n != 1 {
+		return fmt.Errorf("the orchestrator did not release the process")
+	}
+	ready.Close()
+
+	// Les rlimits sont fixées au dernier moment : RLIMIT_NPROC pourrait sinon empêcher le
+	// runtime de créer ses threads
+	for _, l := range spec.Rlimits {
+		limit := syscall.Rlimit{Cur: l.Cur, Max: l.Max}
+		if err := syscall.Setrlimit(l.Resource, &limit); err != nil {
+			return fmt.Errorf("could not set resource limit %d: %v", l.Resource, err)
+		}
+	}
+	if err := syscall.Exec(path, argv, os.Environ()); err != nil {
+		return fmt.Errorf("could not execute %s: %v", path, err)
+	}
+	return nil
+}
+
+// processLimiter suit les limites d'un processus démarré par le relais
+type processLimiter struct {
+	cgroup *cgroup  // nil lorsque les limites reposent uniquement sur les rlimits
+	child  *os.File // Extrémité du tube transmise au relais
+	ready  *os.File // Extrémité sur laquelle l'orchestrateur libère le relais
+}
+
+func (p *processLimiter) start(pid int) error {
+	p.child.Close()
+	defer p.ready.Close()
+	if p.cgroup != nil {
+		if err := p.cgroup.addProcess(pid); err != nil {
+			return err
+		}
+	}
+	_, err := p.ready.Write([]byte{1})
+	return err
+}
+
+func (p *processLimiter) failureReason(status ExitStatus) string {
+	// Une limite atteinte sans incidence sur le résultat, comme un fork refusé puis réessayé,
+	// ne fait pas échouer le job
+	if status.Code == 0 && status.Signal == "" {
+		return ""
+	}
+	if p.cgroup != nil {
+		return p.cgroup.failureReason()
+	}
+	// Un shell ou l'init du bac à sable rapporte la fin de la commande par signal avec le code 128 + signal
+	if status.Signal == syscall.SIGXCPU.String() || status.Code == 128+int(syscall.SIGXCPU) {
+		return models.FailureLimitExceeded
+	}
+	return ""
+}
+
+func (p *processLimiter) release() {
+	if p.child != nil {
+		p.child.Close()
+		p.ready.Close()
+	}
+	if p.cgroup != nil {
+		p.cgroup.release()
+	}
+}
+
+func cgroupV2Available(path string) bool {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return false
+	}
+	return fs.Type == cgroup2SuperMagic
+}
+
+// cgroup est le cgroup v2 dédié à une tentative
+type cgroup struct {
+	path string
+}
+
+func newCgroup(root, name string, l *models.ResourceLimits) (*cgroup, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	// Les contrôleurs doivent être délégués aux cgroups enfants de la racine
+	if err := writeCgroupFile(root, "cgroup.subtree_control", "+memory +cpu +pids"); err != nil {
+		return nil, err
+	}
+
+	cg := &cgroup{path: filepath.Join(root, name)}
+	if err := os.Mkdir(cg.path, 0755); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if l.MemoryBytes > 0 {
+		err = writeCgroupFile(cg.path, "memory.max", strconv.FormatInt(l.MemoryBytes, 10))
+		if err == nil {
+			// Sans swap, le dépassement déclenche directement l'OOM killer
+			writeCgroupFile(cg.path, "memory.swap.max", "0")
+		}
+	}
+	if err == nil && l.CPUs > 0 {
+		quota := int(l.CPUs * cgroupCPUPeriod)
+		err = writeCgroupFile(cg.path, "cpu.max", fmt.Sprintf("%d %d", quota, cgroupCPUPeriod))
+	}
+	if err == nil && l.MaxProcesses > 0 {
+		err = writeCgroupFile(cg.path, "pids.max", strconv.Itoa(l.MaxProcesses))
+	}
+	if err != nil {
+		cg.release()
+		return nil, err
+	}
+	return cg, nil
+}
+
+func (c *cgroup) addProcess(pid int) error {
+	return writeCgroupFile(c.path, "cgroup.procs", strconv.Itoa(pid))
+}
+
+func (c *cgroup) failureReason() string {
+	if cgroupEventCount(c.path, "memory.events", "oom_kill") > 0 {
+		return models.FailureOOMKilled
+	}
+	if cgroupEventCount(c.path, "pids.events", "max") > 0 {
+		return models.FailureLimitExceeded
+	}
+	return ""
+}
+
+// release tue les processus restants du cgroup et le supprime une fois vide
+func (c *cgroup) release() {
+	if err := writeCgroupFile(c.path, "cgroup.kill", "1"); err != nil {
+		// cgroup.kill n'existe qu'à partir de Linux 5.14
+		c.killProcesses()
+	}
+	deadline := time.Now().Add(cgroupDrainTimeout)
+	for cgroupEventCount(c.path, "cgroup.events", "populated") > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+		logger.Warning(fmt.Sprintf("Could not remove cgroup %s: %v", c.path, err))
+	}
+}
+
+func (c *cgroup) killProcesses() {
+	data, err := ioutil.ReadFile(filepath.Join(c.path, "cgroup.procs"))
+	if err != nil {
+		return
+	}
+	for _, field := range strings.Fields(string(data)) {
+		if pid, err := strconv.Atoi(field); err == nil {
+			syscall.Kill(pid, syscall.SIGKILL)
+		}
+	}
+}
+
+func writeCgroupFile(dir, name, value string) error {
+	return ioutil.WriteFile(filepath.Join(dir, name), []byte(value), 0644)
+}
+
+// cgroupEventCount lit un compteur d'un fichier d'événements tel que memory.events
+func cgroupEventCount(dir, file, key string) int {
+	f, err := os.Open(filepath.Join(dir, file))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == key {
+			count, _ := strconv.Atoi(fields[1])
+			return count
+		}
+	}
+	return 0
+}
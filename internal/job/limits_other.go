@@ -0,0 +1,22 @@
+//go:build !linux
+// +build !linux
+
+package job
+
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+)
+
+// applyLimits n'est disponible que sous Linux
+func (r *Runner) applyLimits(j *models.Job, cmd *exec.Cmd) (limiter, error) {
+	if j.Limits == nil {
+		return noLimiter{}, nil
+	}
+	return nil, fmt.Errorf("resource limits are only supported on Linux")
+}
+
+// InitLimits ne fait rien hors de Linux
+func InitLimits() {}
@@ -0,0 +1,57 @@
+package job
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// ValidStream indique si le nom de flux correspond à stdout ou stderr
+func ValidStream(stream string) bool {
+	return stream == StreamStdout || stream == StreamStderr
+}
+
+// LogPath retourne le chemin du fichier de log d'une tentative pour un flux donné
+func LogPath(dir, jobID string, attempt int, stream string) string {
+	return filepath.Join(jobLogDir(dir, jobID), fmt.Sprintf("attempt-%d.%s.log", attempt, stream))
+}
+
+func jobLogDir(dir, jobID string) string {
+	return filepath.Join(dir, jobID)
+}
+
+// ReadTail lit un fichier de log et retourne ses n dernières lignes (tout le fichier si n <= 0),
+// ainsi que la position de fin de lecture pour un suivi ultérieur
+func ReadTail(path string, n int) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	var lines []string
+	var offset int64
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			offset += int64(len(line))
+			lines = append(lines, line)
+			if n > 0 && len(lines) > n {
+				lines = lines[1:]
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	return strings.Join(lines, ""), offset, nil
+}
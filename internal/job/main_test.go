@@ -0,0 +1,28 @@
+package job
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chrlesur/orchestrator/pkg/logger"
+)
+
+// TestMain initialise le journal une seule fois : des workers d'un test précédent peuvent
+// encore écrire dans le journal lorsque le test suivant démarre
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "orchestrator-job-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := logger.Init("error", filepath.Join(dir, "orchestrator.log")); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
@@ -2,37 +2,52 @@ package job
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/chrlesur/orchestrator/internal/db"
+	"github.com/chrlesur/orchestrator/internal/lock"
 	"github.com/chrlesur/orchestrator/internal/models"
 	"github.com/chrlesur/orchestrator/internal/plugin"
+	"github.com/chrlesur/orchestrator/internal/workerpool"
 	"github.com/chrlesur/orchestrator/pkg/logger"
 	"github.com/chrlesur/orchestrator/pkg/utils"
 )
 
 type Manager struct {
-	jobs           map[string]*models.Job
-	jobQueue       chan *models.Job
-	mu             sync.Mutex
-	wg             sync.WaitGroup
-	store          *db.Store
-	defaultTimeout time.Duration
-	maxRetries     int
-	pluginManager  *plugin.PluginManager
+	jobs       map[string]*models.Job
+	queue      *queue
+	mu         sync.Mutex
+	wg         sync.WaitGroup
+	store      *db.Store
+	settings   Settings
+	runner     *Runner
+	running    map[string]context.CancelFunc
+	workers    *workerpool.Pool
+	locks      *lock.Registry
+	lockWaits  map[string]*models.Job // Jobs sortis de la file en attente de leurs verrous
+	dependents map[string][]string    // Jobs bloqués en attente de chaque job
+	recovery   RecoveryReport
 }
 
-func NewManager(workerCount int, store *db.Store, defaultTimeout time.Duration, maxRetries int, pluginManager *plugin.PluginManager) *Manager {
+func NewManager(workerCount int, store *db.Store, settings Settings, pluginManager *plugin.PluginManager) *Manager {
 	m := &Manager{
-		jobs:           make(map[string]*models.Job),
-		jobQueue:       make(chan *models.Job, 100),
-		store:          store,
-		defaultTimeout: defaultTimeout,
-		maxRetries:     maxRetries,
-		pluginManager:  pluginManager,
+		jobs:       make(map[string]*models.Job),
+		queue:      newQueue(),
+		store:      store,
+		settings:   settings,
+		runner:     NewRunner(settings, store),
+		running:    make(map[string]context.CancelFunc),
+		locks:      lock.NewRegistry(),
+		lockWaits:  make(map[string]*models.Job),
+		dependents: make(map[string][]string),
 	}
+	m.runner.plugins = pluginManager
 
 	// Charger les jobs existants depuis la base de données
 	jobs, err := store.GetAllJobs()
@@ -42,34 +57,165 @@ func NewManager(workerCount int, store *db.Store, defaultTimeout time.Duration,
 		for _, job := range jobs {
 			m.jobs[job.ID] = job
 		}
+		m.recoverJobs(jobs)
+		m.requeuePending(jobs)
+		m.restoreDependencies(jobs)
 	}
 
-	for i := 0; i < workerCount; i++ {
-		go m.worker()
+	m.workers = workerpool.New(m.worker)
+	if err := m.workers.Resize(workerCount); err != nil {
+		logger.Error(fmt.Sprintf("Failed to start job workers: %v", err))
 	}
+	go m.requeueLockWaits()
 
 	return m
 }
 
+// ResizeWorkers modifie à chaud le nombre de workers sans interrompre les jobs en cours
+func (m *Manager) ResizeWorkers(count int) error {
+	if err := m.workers.Resize(count); err != nil {
+		return err
+	}
+	m.queue.Wake()
+	logger.Info(fmt.Sprintf("Job worker pool resized to %d", count))
+	return nil
+}
+
+// WorkerStats retourne la taille du pool de workers et le nombre de workers occupés
+func (m *Manager) WorkerStats() workerpool.Stats {
+	return m.workers.Stats()
+}
+
+// Locks retourne le registre des verrous nommés, partagé avec le gestionnaire de pipelines
+func (m *Manager) Locks() *lock.Registry {
+	return m.locks
+}
+
 func (m *Manager) CreateJob(name, command string, args []string, pluginName string) (*models.Job, error) {
-    id := utils.GenerateID(8)
-    job := &models.Job{
-        ID:         id,
-        Name:       name,
-        Command:    command,
-        Args:       args,
-        PluginName: pluginName,
-        Status:     models.JobStatusPending,
-        Timeout:    m.defaultTimeout,
-        MaxRetries: m.maxRetries,
-    }
-
-    err := m.AddJob(job)
-    if err != nil {
-        return nil, err
-    }
-
-    return job, nil
+	job := m.PrepareJob(name, command, args, pluginName)
+	if err := m.SubmitJob(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// PrepareJob crée un job avec les valeurs par défaut du gestionnaire, sans l'ajouter à la file.
+// L'appelant peut ensuite ajuster sa définition avant de le soumettre avec SubmitJob
+func (m *Manager) PrepareJob(name, command string, args []string, pluginName string) *models.Job {
+	return &models.Job{
+		ID:          utils.GenerateID(8),
+		Name:        name,
+		Command:     command,
+		Args:        args,
+		PluginName:  pluginName,
+		Status:      models.JobStatusPending,
+		Timeout:     m.settings.DefaultTimeout,
+		GracePeriod: m.settings.GracePeriod,
+		MaxRetries:  m.settings.MaxRetries,
+	}
+}
+
+// ErrInvalidJob signale une définition de job refusée par la validation
+var ErrInvalidJob = errors.New("invalid job definition")
+
+// invalidJobError conserve le message de l'erreur de validation d'origine
+type invalidJobError struct {
+	err error
+}
+
+func (e invalidJobError) Error() string        { return e.err.Error() }
+func (e invalidJobError) Unwrap() error        { return e.err }
+func (e invalidJobError) Is(target error) bool { return target == ErrInvalidJob }
+
+// SubmitJob valide la définition d'un job préparé puis l'ajoute à la file d'exécution.
+// Les refus de la validation satisfont errors.Is(err, ErrInvalidJob), ceux de l'identité
+// d'exécution errors.Is(err, ErrRunAsNotAllowed)
+func (m *Manager) SubmitJob(job *models.Job) error {
+	return m.SubmitJobFrom(job, "local")
+}
+
+// SubmitJobFrom soumet un job comme SubmitJob, requester désignant l'origine de la demande
+// dans le journal d'audit
+func (m *Manager) SubmitJobFrom(job *models.Job, requester string) error {
+	if err := m.validateSubmission(job, requester); err != nil {
+		return err
+	}
+	return m.AddJob(job)
+}
+
+// validateSubmission regroupe les vérifications d'un job soumis ou enregistré qui ne
+// dépendent pas des autres jobs
+func (m *Manager) validateSubmission(job *models.Job, requester string) error {
+	if err := ValidateJob(job); err != nil {
+		return invalidJobError{err}
+	}
+	if err := m.ValidateHost(job); err != nil {
+		return invalidJobError{err}
+	}
+	if err := m.AuthorizeRunAs(job, requester); err != nil {
+		if errors.Is(err, ErrRunAsNotAllowed) {
+			return err
+		}
+		return invalidJobError{err}
+	}
+	return nil
+}
+
+// ValidateJob vérifie la définition d'un job avant son exécution
+func ValidateJob(job *models.Job) error {
+	if job.Command == "" && job.PluginName == "" && !IsScript(job) {
+		return fmt.Errorf("job must define a command, a script or a plugin")
+	}
+	if IsScript(job) && (job.Command != "" || job.PluginName != "") {
+		return fmt.Errorf("a script job cannot also define a command or a plugin")
+	}
+	if job.PluginName != "" && job.Host != "" {
+		return fmt.Errorf("a plugin job cannot run on a remote host")
+	}
+	if job.Timeout <= 0 {
+		return fmt.Errorf("job timeout must be positive")
+	}
+	if job.GracePeriod < 0 {
+		return fmt.Errorf("job grace period must not be negative")
+	}
+	if job.MaxRetries < 0 {
+		return fmt.Errorf("job max retries must not be negative")
+	}
+	if err := ValidateRetryPolicy(job.RetryPolicy); err != nil {
+		return err
+	}
+	if err := ValidateSuccessCriteria(job.Success); err != nil {
+		return err
+	}
+	if err := ValidateEnv(job.Env); err != nil {
+		return err
+	}
+	if err := ValidateLimits(job.Limits); err != nil {
+		return err
+	}
+	if err := ValidateIsolation(job.Isolation); err != nil {
+		return err
+	}
+	if err := lock.Validate(job.Locks); err != nil {
+		return err
+	}
+	if err := ValidateDependencyPolicy(job.DependencyPolicy); err != nil {
+		return err
+	}
+	if err := ValidateRecoveryPolicy(job.RecoveryPolicy); err != nil {
+		return err
+	}
+	if err := ValidateResultFormat(job.ResultFormat); err != nil {
+		return err
+	}
+	if err := ValidateArtifactGlobs(job.ArtifactGlobs); err != nil {
+		return err
+	}
+	// Le nom d'étape doit pouvoir être utilisé dans les arguments, par exemple {{.steps.fetch}}
+	if job.StepName != "" && !templateParamName.MatchString(job.StepName) {
+		return fmt.Errorf("invalid step name: %q", job.StepName)
+	}
+	return nil
 }
 
 func (m *Manager) AddJob(job *models.Job) error {
@@ -77,18 +223,96 @@ func (m *Manager) AddJob(job *models.Job) error {
 	defer m.mu.Unlock()
 
 	if _, exists := m.jobs[job.ID]; exists {
-		return fmt.Errorf("job with ID %s already exists", job.ID)
+		return invalidJobError{fmt.Errorf("job with ID %s already exists", job.ID)}
+	}
+	if err := m.checkDependencies(job); err != nil {
+		return invalidJobError{err}
 	}
 
 	m.jobs[job.ID] = job
+	job.QueuedAt = time.Now()
+	ready := m.trackDependencies(job)
 	err := m.store.SaveJob(job)
 	if err != nil {
 		return fmt.Errorf("failed to save job to database: %v", err)
 	}
 
-	m.jobQueue <- job
+	if ready {
+		m.enqueue(job)
+	} else {
+		// Les dépendances peuvent déjà être terminées
+		m.resolve([]string{job.ID})
+	}
+	return nil
+}
+
+// enqueue ajoute un job à la file d'attente prioritaire, sans jamais bloquer
+func (m *Manager) enqueue(job *models.Job) {
 	m.wg.Add(1)
+	if err := m.queue.Push(job); err != nil {
+		m.wg.Done()
+		logger.Error(fmt.Sprintf("Failed to queue job %s: %v", job.ID, err))
+	}
+}
 
+// requeuePending remet en file les jobs restés en attente lors du précédent arrêt,
+// dans leur ordre d'arrivée initial. Les étapes de pipeline ne sont pas concernées
+func (m *Manager) requeuePending(jobs []*models.Job) {
+	pending := make([]*models.Job, 0)
+	for _, job := range jobs {
+		if job.Status == models.JobStatusPending && job.PipelineID == "" {
+			pending = append(pending, job)
+		}
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].QueuedAt.Before(pending[j].QueuedAt)
+	})
+	for _, job := range pending {
+		m.enqueue(job)
+	}
+	if len(pending) > 0 {
+		logger.Info(fmt.Sprintf("Requeued %d pending jobs from database", len(pending)))
+	}
+}
+
+// PendingJobs retourne les jobs en attente dans l'ordre où ils seront exécutés
+func (m *Manager) PendingJobs() []*models.Job {
+	return m.queue.Pending()
+}
+
+// QueueStats retourne la profondeur de la file et les temps d'attente
+func (m *Manager) QueueStats() QueueStats {
+	return m.queue.Stats()
+}
+
+// RegisterJob valide et enregistre un job sans l'ajouter à la file d'exécution,
+// par exemple lorsqu'il n'est exécuté que comme étape d'un pipeline. Les refus sont
+// signalés comme pour SubmitJob
+func (m *Manager) RegisterJob(job *models.Job) error {
+	return m.RegisterJobFrom(job, "local")
+}
+
+// RegisterJobFrom enregistre un job comme RegisterJob, requester désignant l'origine de
+// la demande dans le journal d'audit
+func (m *Manager) RegisterJobFrom(job *models.Job, requester string) error {
+	if err := m.validateSubmission(job, requester); err != nil {
+		return err
+	}
+	if len(job.DependsOn) > 0 {
+		return invalidJobError{fmt.Errorf("pipeline steps cannot declare dependencies")}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.jobs[job.ID]; exists {
+		return invalidJobError{fmt.Errorf("job with ID %s already exists", job.ID)}
+	}
+
+	if err := m.store.SaveJob(job); err != nil {
+		return fmt.Errorf("failed to save job to database: %v", err)
+	}
+	m.jobs[job.ID] = job
 	return nil
 }
 
@@ -122,50 +346,222 @@ func (m *Manager) GetJobs() []*models.Job {
 }
 
 func (m *Manager) worker() {
-	for job := range m.jobQueue {
+	for {
+		job, ok := m.queue.Pop(m.workers.Retire)
+		if !ok {
+			return
+		}
+		if job.Status == models.JobStatusCancelled {
+			logger.Info(fmt.Sprintf("Skipping cancelled job %s", job.ID))
+			m.wg.Done()
+			continue
+		}
+		release, ok := m.claimLocks(job)
+		if !ok {
+			continue
+		}
 		logger.Info(fmt.Sprintf("Starting job %s", job.ID))
+		m.workers.Begin()
 		start := time.Now()
-		var err error
-		if job.PluginName != "" {
-			err = m.executePluginJob(job)
-		} else {
-			err = Execute(job, context.Background())
-		}
+		err := m.execute(job, func(context.Context) (func(), error) { return release, nil })
+		release()
 		duration := time.Since(start)
-		if err != nil {
+		m.workers.Done()
+		if job.Status == models.JobStatusCancelled {
+			logger.Info(fmt.Sprintf("Job %s cancelled after %s", job.ID, utils.FormatDuration(duration)))
+		} else if err != nil {
 			logger.Error(fmt.Sprintf("Job %s failed after %s: %v", job.ID, utils.FormatDuration(duration), err))
 		} else {
 			logger.Info(fmt.Sprintf("Job %s completed successfully in %s", job.ID, utils.FormatDuration(duration)))
 		}
-		m.store.SaveJob(job) // Sauvegarder l'état final du job
+		m.resolveDependents(job.ID)
 		m.wg.Done()
 	}
 }
 
-func (m *Manager) executePluginJob(job *models.Job) error {
-	args := make(map[string]interface{})
-	for i, arg := range job.Args {
-		args[fmt.Sprintf("arg%d", i)] = arg
+// claimLocks prend sans attendre les verrous d'un job sorti de la file. Lorsqu'ils ne sont pas
+// tous disponibles, le job est mis de côté sans occuper de worker jusqu'à la prochaine
+// libération d'un verrou ; un verrou demandé avec une limite incompatible fait échouer le job
+func (m *Manager) claimLocks(job *models.Job) (func(), bool) {
+	m.mu.Lock()
+	release, ok, err := m.locks.TryAcquire(jobOwner(job, job.PipelineID), job.Locks)
+	if err == nil {
+		if !ok {
+			if _, waiting := m.lockWaits[job.ID]; !waiting {
+				logger.Info(fmt.Sprintf("Job %s waiting for locks", job.ID))
+			}
+			m.lockWaits[job.ID] = job
+		}
+		m.mu.Unlock()
+		return release, ok
+	}
+
+	job.Status = models.JobStatusFailed
+	job.Error = err
+	job.EndTime = time.Now()
+	if saveErr := m.store.SaveJob(job); saveErr != nil {
+		logger.Error(fmt.Sprintf("Failed to save job %s: %v", job.ID, saveErr))
+	}
+	m.mu.Unlock()
+	logger.Error(fmt.Sprintf("Job %s could not take its locks: %v", job.ID, err))
+	m.resolveDependents(job.ID)
+	m.wg.Done()
+	return nil, false
+}
+
+// requeueLockWaits remet en file les jobs en attente de verrous à chaque libération d'un verrou.
+// Après l'arrêt de la file, ils restent en attente et sont repris au prochain démarrage
+func (m *Manager) requeueLockWaits() {
+	for {
+		<-m.locks.Changed()
+		m.mu.Lock()
+		for id, job := range m.lockWaits {
+			delete(m.lockWaits, id)
+			m.locks.Forget(jobOwner(job, job.PipelineID), job.Locks)
+			if err := m.queue.Push(job); err != nil {
+				m.wg.Done()
+			}
+		}
+		m.mu.Unlock()
+	}
+}
+
+func jobOwner(job *models.Job, pipelineID string) lock.Owner {
+	return lock.Owner{Kind: lock.OwnerJob, ID: job.ID, Parent: pipelineID}
+}
+
+// RunJob exécute un job de manière synchrone tout en le rendant annulable via CancelJob
+func (m *Manager) RunJob(job *models.Job) error {
+	return m.RunStep(job, job.PipelineID)
+}
+
+// RunStep exécute un job comme étape du pipeline pipelineID, dont il peut utiliser les verrous.
+// Un job autonome référencé par le pipeline reste autonome : son PipelineID n'est pas modifié.
+// Les verrous sont attendus avant le démarrage ; une annulation interrompt l'attente
+func (m *Manager) RunStep(job *models.Job, pipelineID string) error {
+	return m.execute(job, func(ctx context.Context) (func(), error) {
+		if len(job.Locks) > 0 {
+			logger.Info(fmt.Sprintf("Job %s waiting for locks", job.ID))
+		}
+		return m.locks.Acquire(ctx, jobOwner(job, pipelineID), job.Locks)
+	})
+}
+
+// execute exécute un job annulable via CancelJob, une fois ses verrous obtenus par acquire
+func (m *Manager) execute(job *models.Job, acquire func(context.Context) (func(), error)) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m.mu.Lock()
+	if job.Status == models.JobStatusCancelled {
+		m.mu.Unlock()
+		return fmt.Errorf("job %s cancelled", job.ID)
 	}
+	m.running[job.ID] = cancel
+	m.mu.Unlock()
 
-	result, err := m.pluginManager.ExecutePlugin(job.PluginName, args)
+	defer func() {
+		m.mu.Lock()
+		delete(m.running, job.ID)
+		m.mu.Unlock()
+	}()
+	// L'état final est enregistré, pour les jobs de la file comme pour les étapes de pipeline
+	defer func() {
+		if err := m.store.SaveJob(job); err != nil {
+			logger.Error(fmt.Sprintf("Failed to save job %s: %v", job.ID, err))
+		}
+	}()
+
+	release, err := acquire(ctx)
 	if err != nil {
+		job.EndTime = time.Now()
+		if ctx.Err() != nil {
+			return cancelled(job)
+		}
 		job.Status = models.JobStatusFailed
 		job.Error = err
-		return err
+		return fmt.Errorf("job %s could not take its locks: %v", job.ID, err)
 	}
+	defer release()
 
-	job.Result = fmt.Sprintf("%v", result)
-	job.Status = models.JobStatusCompleted
+	return m.runner.Execute(job, ctx)
+}
+
+// CancelJob annule un job en cours d'exécution ou encore en attente dans la file
+func (m *Manager) CancelJob(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, exists := m.jobs[id]
+	if !exists {
+		return fmt.Errorf("job with ID %s not found", id)
+	}
+
+	if cancel, running := m.running[id]; running {
+		cancel()
+		logger.Info(fmt.Sprintf("Cancellation requested for job %s", id))
+		return nil
+	}
+
+	if job.Status != models.JobStatusPending && job.Status != models.JobStatusBlocked {
+		return fmt.Errorf("job %s cannot be cancelled in status %s", id, job.Status)
+	}
+
+	if m.queue.Remove(id) {
+		m.wg.Done()
+	} else if _, waiting := m.lockWaits[id]; waiting {
+		delete(m.lockWaits, id)
+		m.locks.Forget(jobOwner(job, job.PipelineID), job.Locks)
+		m.wg.Done()
+	}
+	job.Status = models.JobStatusCancelled
+	job.EndTime = time.Now()
+	if err := m.store.SaveJob(job); err != nil {
+		return fmt.Errorf("failed to save job to database: %v", err)
+	}
+	logger.Info(fmt.Sprintf("Pending job %s cancelled", id))
+	m.resolve(m.dependents[id])
 	return nil
 }
 
+// GetAttempts retourne l'historique des tentatives d'un job
+func (m *Manager) GetAttempts(id string) ([]*models.JobAttempt, error) {
+	if _, err := m.GetJob(id); err != nil {
+		return nil, err
+	}
+	return m.store.GetAttempts(id)
+}
+
+// LogPath retourne le fichier de log d'un flux pour une tentative d'un job.
+// Un numéro de tentative nul désigne la tentative la plus récente
+func (m *Manager) LogPath(id string, attempt int, stream string) (string, error) {
+	if m.settings.LogDir == "" {
+		return "", fmt.Errorf("job logs are disabled")
+	}
+	if !ValidStream(stream) {
+		return "", fmt.Errorf("invalid stream %q", stream)
+	}
+
+	job, err := m.GetJob(id)
+	if err != nil {
+		return "", err
+	}
+	if attempt <= 0 {
+		attempt = job.Attempt
+	}
+	if attempt <= 0 || attempt > job.Attempt {
+		return "", fmt.Errorf("job %s has no attempt %d", id, attempt)
+	}
+
+	return LogPath(m.settings.LogDir, id, attempt, stream), nil
+}
+
 func (m *Manager) Wait() {
 	m.wg.Wait()
 }
 
 func (m *Manager) Shutdown() {
-	close(m.jobQueue)
+	m.queue.Close()
 	m.Wait()
 }
 
@@ -180,3 +576,122 @@ func (m *Manager) UpdateJob(job *models.Job) error {
 	m.jobs[job.ID] = job
 	return m.store.SaveJob(job)
 }
+
+// IsFinished indique si un job a atteint un état définitif
+func IsFinished(status models.JobStatus) bool {
+	switch status {
+	case models.JobStatusCompleted, models.JobStatusFailed, models.JobStatusCancelled, models.JobStatusSkipped, models.JobStatusLost:
+		return true
+	}
+	return false
+}
+
+// DeleteJob supprime un job terminé, l'historique de ses tentatives et ses fichiers de log,
+// de sortie et de travail. Un job attendu par un job bloqué est conservé
+func (m *Manager) DeleteJob(id string) error {
+	return m.deleteJob(id, false)
+}
+
+// DeleteStep supprime une étape d'un pipeline terminé. Une étape que le pipeline n'a pas
+// atteinte est encore en attente et peut aussi être supprimée
+func (m *Manager) DeleteStep(id string) error {
+	return m.deleteJob(id, true)
+}
+
+func (m *Manager) deleteJob(id string, step bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, exists := m.jobs[id]
+	if !exists {
+		return fmt.Errorf("job with ID %s not found", id)
+	}
+	unreached := step && job.Status == models.JobStatusPending
+	if _, running := m.running[id]; running || !(IsFinished(job.Status) || unreached) {
+		return fmt.Errorf("job %s cannot be deleted in status %s", id, job.Status)
+	}
+	for _, dependent := range m.dependents[id] {
+		if d, ok := m.jobs[dependent]; ok && d.Status == models.JobStatusBlocked {
+			return fmt.Errorf("job %s is still awaited by job %s", id, dependent)
+		}
+	}
+
+	artifacts, err := m.store.GetArtifacts(id)
+	if err != nil {
+		return err
+	}
+	if err := m.store.DeleteJob(id); err != nil {
+		return fmt.Errorf("failed to delete job from database: %v", err)
+	}
+	m.removeArtifacts(artifacts)
+	delete(m.jobs, id)
+	delete(m.dependents, id)
+	for _, upstream := range job.DependsOn {
+		m.dependents[upstream] = removeID(m.dependents[upstream], id)
+	}
+
+	dirs := []string{}
+	if m.settings.LogDir != "" {
+		dirs = append(dirs, jobLogDir(m.settings.LogDir, id))
+	}
+	if m.settings.OutputDir != "" {
+		dirs = append(dirs, filepath.Join(m.settings.OutputDir, id))
+	}
+	removeDirs(dirs...)
+	if m.settings.WorkspaceDir != "" && job.PipelineID == "" {
+		if workspace, err := WorkspacePath(m.settings.WorkspaceDir, job); err == nil {
+			m.removeWorkspace(workspace)
+		}
+	}
+
+	logger.Debug(fmt.Sprintf("Job %s deleted", id))
+	return nil
+}
+
+// DeletePipelineWorkspace supprime le répertoire de travail partagé d'un pipeline
+func (m *Manager) DeletePipelineWorkspace(pipelineID string) {
+	if m.settings.WorkspaceDir == "" {
+		return
+	}
+	workspace, err := PipelineWorkspacePath(m.settings.WorkspaceDir, pipelineID)
+	if err != nil {
+		logger.Warning(fmt.Sprintf("Not removing workspace of pipeline %q: %v", pipelineID, err))
+		return
+	}
+	m.removeWorkspace(workspace)
+}
+
+// removeWorkspace supprime un répertoire de travail après avoir vérifié, liens résolus, qu'il
+// se trouve toujours sous la racine des workspaces
+func (m *Manager) removeWorkspace(path string) {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return
+	}
+	root, rootErr := filepath.EvalSymlinks(m.settings.WorkspaceDir)
+	resolved, resolveErr := filepath.EvalSymlinks(path)
+	if err != nil || rootErr != nil || resolveErr != nil || !info.IsDir() ||
+		resolved == root || !withinDir(root, resolved) {
+		logger.Warning(fmt.Sprintf("Not removing %s: it is not a workspace under %s", path, m.settings.WorkspaceDir))
+		return
+	}
+	removeDirs(path)
+}
+
+func removeDirs(dirs ...string) {
+	for _, dir := range dirs {
+		if err := os.RemoveAll(dir); err != nil {
+			logger.Warning(fmt.Sprintf("Could not remove %s: %v", dir, err))
+		}
+	}
+}
+
+func removeID(ids []string, id string) []string {
+	kept := ids[:0]
+	for _, existing := range ids {
+		if existing != id {
+			kept = append(kept, existing)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,128 @@
+package job
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// OutputPath retourne le fichier recevant la sortie complète d'une tentative dont stdout
+// a dépassé la taille maximale conservée sur le job, lorsque les logs des tentatives sont
+// désactivés
+func OutputPath(dir, jobID string, attempt int) string {
+	return filepath.Join(dir, jobID, fmt.Sprintf("attempt-%d.stdout", attempt))
+}
+
+// cappedOutput conserve en mémoire au plus limit octets de la sortie d'un processus.
+// Dès que la limite est dépassée, l'intégralité de la sortie est écrite dans le fichier
+// path ; sans fichier, le surplus est ignoré. Une limite nulle désactive le plafond
+type cappedOutput struct {
+	limit     int
+	path      string
+	preview   bytes.Buffer
+	file      *os.File
+	spilled   bool
+	truncated bool
+	err       error
+}
+
+func newCappedOutput(limit int, path string) *cappedOutput {
+	return &cappedOutput{limit: limit, path: path}
+}
+
+func (c *cappedOutput) Write(p []byte) (int, error) {
+	if c.limit <= 0 || (!c.truncated && c.preview.Len()+len(p) <= c.limit) {
+		return c.preview.Write(p)
+	}
+
+	if !c.truncated {
+		c.truncated = true
+		if c.path != "" {
+			c.file, c.err = createOutputFile(c.path)
+			if c.err == nil {
+				c.spilled = true
+				_, c.err = c.file.Write(c.preview.Bytes())
+			}
+		}
+		c.preview.Write(p[:c.limit-c.preview.Len()])
+	}
+
+	// Une erreur d'écriture du fichier ne doit pas interrompre le processus
+	if c.file != nil && c.err == nil {
+		_, c.err = c.file.Write(p)
+	}
+	return len(p), nil
+}
+
+// String retourne la sortie conservée en mémoire, tronquée si la limite a été dépassée
+func (c *cappedOutput) String() string {
+	return c.preview.String()
+}
+
+// overflowPath retourne le fichier contenant la sortie complète, vide si elle n'a pas débordé
+func (c *cappedOutput) overflowPath() string {
+	if !c.spilled || c.err != nil {
+		return ""
+	}
+	return c.path
+}
+
+func (c *cappedOutput) Close() error {
+	if c.file != nil {
+		if err := c.file.Close(); err != nil && c.err == nil {
+			c.err = err
+		}
+		c.file = nil
+	}
+	return c.err
+}
+
+func createOutputFile(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return os.Create(path)
+}
+
+// streamOutput donne accès à la sortie complète d'un flux d'une tentative : l'aperçu lorsqu'il
+// est complet, à défaut le fichier qui la contient
+type streamOutput struct {
+	preview   string
+	truncated bool
+	path      string // Sortie complète d'un aperçu tronqué, vide si elle n'est pas conservée
+}
+
+// match indique si la sortie complète correspond à re. Une sortie tronquée qui n'a pas été
+// conservée ne peut pas être examinée
+func (s streamOutput) match(re *regexp.Regexp) (bool, error) {
+	if !s.truncated {
+		return re.MatchString(s.preview), nil
+	}
+	if s.path == "" {
+		return false, fmt.Errorf("output truncated: it exceeds the maximum output size and is not kept in full")
+	}
+	f, err := os.Open(s.path)
+	if err != nil {
+		return false, fmt.Errorf("could not read the full output: %v", err)
+	}
+	defer f.Close()
+	return re.MatchReader(bufio.NewReader(f)), nil
+}
+
+// attemptOutput regroupe stdout et stderr d'une tentative
+type attemptOutput struct {
+	stdout streamOutput
+	stderr streamOutput
+}
+
+// match indique si stdout ou stderr correspond à re
+func (o attemptOutput) match(re *regexp.Regexp) (bool, error) {
+	matched, err := o.stdout.match(re)
+	if matched || err != nil {
+		return matched, err
+	}
+	return o.stderr.match(re)
+}
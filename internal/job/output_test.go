@@ -0,0 +1,79 @@
+package job
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCappedOutput(t *testing.T) {
+	tests := []struct {
+		name          string
+		limit         int
+		spill         bool
+		writes        []string
+		wantPreview   string
+		wantTruncated bool
+		wantFile      string // Contenu attendu du fichier, vide s'il ne doit pas exister
+	}{
+		{"under the limit", 10, true, []string{"hello"}, "hello", false, ""},
+		{"exactly the limit", 5, true, []string{"he", "llo"}, "hello", false, ""},
+		{"no limit", 0, true, []string{strings.Repeat("x", 100)}, strings.Repeat("x", 100), false, ""},
+		{"over the limit in one write", 4, true, []string{"hello world"}, "hell", true, "hello world"},
+		{"over the limit across writes", 4, true, []string{"he", "llo", " world"}, "hell", true, "hello world"},
+		{"over the limit without file", 4, false, []string{"hello", " world"}, "hell", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := ""
+			if tt.spill {
+				path = filepath.Join(t.TempDir(), "job", "attempt-1.stdout")
+			}
+			c := newCappedOutput(tt.limit, path)
+			for _, w := range tt.writes {
+				if n, err := c.Write([]byte(w)); err != nil || n != len(w) {
+					t.Fatalf("write = %d, %v; want %d", n, err, len(w))
+				}
+			}
+			if err := c.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if c.String() != tt.wantPreview || c.truncated != tt.wantTruncated {
+				t.Fatalf("preview = %q (truncated %v), want %q (truncated %v)", c.String(), c.truncated, tt.wantPreview, tt.wantTruncated)
+			}
+			if tt.wantFile == "" {
+				if c.overflowPath() != "" {
+					t.Fatalf("overflow file %s written", c.overflowPath())
+				}
+				return
+			}
+			data, err := ioutil.ReadFile(c.overflowPath())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.wantFile {
+				t.Fatalf("overflow file = %q, want %q", data, tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestCappedOutputUnwritableFile(t *testing.T) {
+	dir := t.TempDir()
+	blocker := filepath.Join(dir, "blocker")
+	if err := ioutil.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := newCappedOutput(2, filepath.Join(blocker, "attempt-1.stdout"))
+	if n, err := c.Write([]byte("hello")); err != nil || n != 5 {
+		t.Fatalf("write = %d, %v; the process must not see the file error", n, err)
+	}
+	if err := c.Close(); err == nil {
+		t.Fatal("file error not reported on close")
+	}
+	if c.String() != "he" || c.overflowPath() != "" {
+		t.Fatalf("preview = %q, overflow = %q; want %q and no file", c.String(), c.overflowPath(), "he")
+	}
+}
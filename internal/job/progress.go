@@ -0,0 +1,120 @@
+package job
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+	"github.com/chrlesur/orchestrator/pkg/logger"
+)
+
+// Directives reconnues en début de ligne sur stdout
+const (
+	directiveProgress  = "::progress"
+	directiveSetOutput = "::set-output"
+)
+
+// maxDirectiveLine borne la ligne en cours de lecture ; une ligne plus longue n'est pas une directive
+const maxDirectiveLine = 64 * 1024
+
+// progressParser lit stdout ligne par ligne et reporte sur le job les directives
+// ::progress <pourcentage> [message] et ::set-output <clé>=<valeur>
+type progressParser struct {
+	job      *models.Job
+	line     bytes.Buffer
+	overflow bool
+}
+
+func newProgressParser(j *models.Job) *progressParser {
+	return &progressParser{job: j}
+}
+
+func (p *progressParser) Write(data []byte) (int, error) {
+	for _, b := range data {
+		if b == '\n' {
+			if !p.overflow {
+				p.parse(p.line.String())
+			}
+			p.line.Reset()
+			p.overflow = false
+			continue
+		}
+		if p.line.Len() >= maxDirectiveLine {
+			p.overflow = true
+			continue
+		}
+		p.line.WriteByte(b)
+	}
+	return len(data), nil
+}
+
+// Close traite une dernière ligne non terminée par un retour à la ligne
+func (p *progressParser) Close() {
+	if p.line.Len() > 0 && !p.overflow {
+		p.parse(p.line.String())
+	}
+	p.line.Reset()
+}
+
+func (p *progressParser) parse(line string) {
+	if !isDirective(line) {
+		return
+	}
+	fields := strings.SplitN(strings.TrimRight(line, "\r"), " ", 2)
+	rest := ""
+	if len(fields) == 2 {
+		rest = strings.TrimSpace(fields[1])
+	}
+
+	switch fields[0] {
+	case directiveProgress:
+		parts := strings.SplitN(rest, " ", 2)
+		percent, err := strconv.Atoi(parts[0])
+		if err != nil {
+			logger.Debug(fmt.Sprintf("Job %s: ignoring invalid progress %q", p.job.ID, rest))
+			return
+		}
+		if percent < 0 {
+			percent = 0
+		} else if percent > 100 {
+			percent = 100
+		}
+		p.job.Progress = percent
+		if len(parts) == 2 {
+			p.job.StatusMessage = strings.TrimSpace(parts[1])
+		}
+	case directiveSetOutput:
+		kv := strings.SplitN(rest, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			logger.Debug(fmt.Sprintf("Job %s: ignoring invalid output %q", p.job.ID, rest))
+			return
+		}
+		// La table est remplacée plutôt que modifiée : elle peut être lue pendant l'exécution
+		outputs := make(map[string]string, len(p.job.Outputs)+1)
+		for key, value := range p.job.Outputs {
+			outputs[key] = value
+		}
+		outputs[kv[0]] = kv[1]
+		p.job.Outputs = outputs
+	}
+}
+
+// isDirective indique si une ligne de stdout est une directive reconnue
+func isDirective(line string) bool {
+	name := strings.SplitN(strings.TrimRight(line, "\r"), " ", 2)[0]
+	return name == directiveProgress || name == directiveSetOutput
+}
+
+// stripDirectives retire de la sortie les lignes de directives, qui ne font pas partie du résultat
+func stripDirectives(output string) string {
+	lines := strings.Split(output, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !isDirective(line) {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
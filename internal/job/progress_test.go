@@ -0,0 +1,72 @@
+package job
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+)
+
+func TestProgressParser(t *testing.T) {
+	tests := []struct {
+		name        string
+		writes      []string
+		wantPercent int
+		wantMessage string
+		wantOutputs map[string]string
+	}{
+		{"progress with message", []string{"::progress 40 copying files\n"}, 40, "copying files", nil},
+		{"latest progress wins", []string{"::progress 10 start\n::progress 70\n"}, 70, "start", nil},
+		{"clamped progress", []string{"::progress 150 done\n"}, 100, "done", nil},
+		{"negative progress", []string{"::progress -5\n"}, 0, "", nil},
+		{"invalid progress", []string{"::progress half\n"}, 0, "", nil},
+		{"split across writes", []string{"::prog", "ress 55 half", "way\n"}, 55, "halfway", nil},
+		{"last line without newline", []string{"::progress 80 almost"}, 80, "almost", nil},
+		{"windows line ending", []string{"::progress 30\r\n"}, 30, "", nil},
+		{"not at the start of the line", []string{"echo ::progress 50\n"}, 0, "", nil},
+		{"outputs", []string{"::set-output version=1.2.3\n::set-output url=http://host/?a=b\n"}, 0, "", map[string]string{"version": "1.2.3", "url": "http://host/?a=b"}},
+		{"output replaced", []string{"::set-output version=1\n::set-output version=2\n"}, 0, "", map[string]string{"version": "2"}},
+		{"empty output value", []string{"::set-output flag=\n"}, 0, "", map[string]string{"flag": ""}},
+		{"invalid outputs", []string{"::set-output novalue\n::set-output =x\n"}, 0, "", nil},
+		{"line too long", []string{"::progress 90 " + strings.Repeat("x", maxDirectiveLine) + "\n"}, 0, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := &models.Job{ID: "progress"}
+			p := newProgressParser(j)
+			for _, w := range tt.writes {
+				p.Write([]byte(w))
+			}
+			p.Close()
+
+			if j.Progress != tt.wantPercent || j.StatusMessage != tt.wantMessage {
+				t.Fatalf("progress = %d %q, want %d %q", j.Progress, j.StatusMessage, tt.wantPercent, tt.wantMessage)
+			}
+			if len(j.Outputs) > 0 || len(tt.wantOutputs) > 0 {
+				if !reflect.DeepEqual(j.Outputs, tt.wantOutputs) {
+					t.Fatalf("outputs = %v, want %v", j.Outputs, tt.wantOutputs)
+				}
+			}
+		})
+	}
+}
+
+func TestStripDirectives(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"no directive", "line 1\nline 2\n", "line 1\nline 2\n"},
+		{"directives removed", "::progress 10\nresult\n::set-output k=v\n", "result\n"},
+		{"unknown directive kept", "::warning disk\nresult", "::warning disk\nresult"},
+		{"windows line endings", "::progress 10\r\nresult\r\n", "result\r\n"},
+		{"only directives", "::progress 100", ""},
+	}
+	for _, tt := range tests {
+		if got := stripDirectives(tt.output); got != tt.want {
+			t.Errorf("%s: stripDirectives = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package job
+
+import (
+	"container/heap"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+)
+
+// Niveaux de priorité nommés. Toute valeur entière est acceptée, la plus grande passant en premier
+const (
+	PriorityLow      = -10
+	PriorityNormal   = 0
+	PriorityHigh     = 10
+	PriorityCritical = 20
+)
+
+// ParsePriority convertit un niveau nommé (low, normal, high, critical) ou un entier en priorité
+func ParsePriority(value string) (int, error) {
+	switch strings.ToLower(value) {
+	case "low":
+		return PriorityLow, nil
+	case "", "normal":
+		return PriorityNormal, nil
+	case "high":
+		return PriorityHigh, nil
+	case "critical":
+		return PriorityCritical, nil
+	}
+	priority, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid priority %q", value)
+	}
+	return priority, nil
+}
+
+// QueueStats résume l'état de la file d'attente
+type QueueStats struct {
+	Depth       int
+	OldestWait  time.Duration
+	AverageWait time.Duration
+}
+
+// queue est la file d'attente non bornée des jobs : les jobs de plus haute priorité sortent
+// en premier et l'ordre d'arrivée est respecté au sein d'une même priorité
+type queue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	items  queueHeap
+	seq    uint64
+	closed bool
+}
+
+type queueItem struct {
+	job   *models.Job
+	seq   uint64
+	index int
+}
+
+func newQueue() *queue {
+	q := &queue{}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// Push ajoute un job à la file sans jamais bloquer
+func (q *queue) Push(j *models.Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return fmt.Errorf("job queue is closed")
+	}
+	if j.QueuedAt.IsZero() {
+		j.QueuedAt = time.Now()
+	}
+	q.seq++
+	heap.Push(&q.items, &queueItem{job: j, seq: q.seq})
+	q.cond.Signal()
+	return nil
+}
+
+// Pop attend le prochain job. Le booléen est faux lorsque la file est fermée et vide,
+// ou lorsque stop, évalué avant chaque prise de job et à chaque réveil, retourne vrai
+func (q *queue) Pop(stop func() bool) (*models.Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		if stop() {
+			return nil, false
+		}
+		if len(q.items) > 0 {
+			item := heap.Pop(&q.items).(*queueItem)
+			return item.job, true
+		}
+		if q.closed {
+			return nil, false
+		}
+		q.cond.Wait()
+	}
+}
+
+// Wake réveille les workers en attente pour qu'ils réévaluent leur condition d'arrêt
+func (q *queue) Wake() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.cond.Broadcast()
+}
+
+// Remove retire un job encore en attente et indique s'il a été trouvé
+func (q *queue) Remove(id string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, item := range q.items {
+		if item.job.ID == id {
+			heap.Remove(&q.items, item.index)
+			return true
+		}
+	}
+	return false
+}
+
+// Close débloque les workers en attente ; les jobs restants sont encore distribués
+func (q *queue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// Pending retourne les jobs en attente dans leur ordre de sortie
+func (q *queue) Pending() []*models.Job {
+	q.mu.Lock()
+	items := make(queueHeap, len(q.items))
+	for i, item := range q.items {
+		copied := *item
+		items[i] = &copied
+	}
+	q.mu.Unlock()
+
+	jobs := make([]*models.Job, 0, len(items))
+	for len(items) > 0 {
+		jobs = append(jobs, heap.Pop(&items).(*queueItem).job)
+	}
+	return jobs
+}
+
+// Stats calcule la profondeur de la file et le temps d'attente des jobs en attente
+func (q *queue) Stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := QueueStats{Depth: len(q.items)}
+	if stats.Depth == 0 {
+		return stats
+	}
+
+	now := time.Now()
+	var total time.Duration
+	for _, item := range q.items {
+		wait := now.Sub(item.job.QueuedAt)
+		total += wait
+		if wait > stats.OldestWait {
+			stats.OldestWait = wait
+		}
+	}
+	stats.AverageWait = total / time.Duration(stats.Depth)
+	return stats
+}
+
+// queueHeap implémente heap.Interface
+type queueHeap []*queueItem
+
+func (h queueHeap) Len() int { return len(h) }
+
+func (h queueHeap) Less(i, j int) bool {
+	if h[i].job.Priority != h[j].job.Priority {
+		return h[i].job.Priority > h[j].job.Priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h queueHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *queueHeap) Push(x interface{}) {
+	item := x.(*queueItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *queueHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
@@ -0,0 +1,95 @@
+package job
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+)
+
+func TestQueueOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		priorities []int
+		removed    string
+		want       []string
+	}{
+		{"arrival order", []int{0, 0, 0}, "", []string{"j0", "j1", "j2"}},
+		{"highest priority first", []int{PriorityLow, PriorityCritical, PriorityNormal, PriorityHigh}, "", []string{"j1", "j3", "j2", "j0"}},
+		{"arrival order within a priority", []int{PriorityHigh, 0, PriorityHigh, 0}, "", []string{"j0", "j2", "j1", "j3"}},
+		{"negative priorities", []int{-5, -1, -5}, "", []string{"j1", "j0", "j2"}},
+		{"removed job", []int{0, PriorityHigh, 0}, "j1", []string{"j0", "j2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newQueue()
+			for i, priority := range tt.priorities {
+				if err := q.Push(&models.Job{ID: "j" + strconv.Itoa(i), Priority: priority}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.removed != "" && !q.Remove(tt.removed) {
+				t.Fatalf("job %s not found in the queue", tt.removed)
+			}
+
+			if got := jobIDs(q.Pending()); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("pending order = %v, want %v", got, tt.want)
+			}
+			q.Close()
+			var popped []*models.Job
+			for {
+				j, ok := q.Pop(func() bool { return false })
+				if !ok {
+					break
+				}
+				popped = append(popped, j)
+			}
+			if got := jobIDs(popped); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("pop order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueueClosed(t *testing.T) {
+	q := newQueue()
+	q.Close()
+	if err := q.Push(&models.Job{ID: "late"}); err == nil {
+		t.Fatal("push accepted by a closed queue")
+	}
+	if _, ok := q.Pop(func() bool { return false }); ok {
+		t.Fatal("pop returned a job from an empty closed queue")
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{"", PriorityNormal, false},
+		{"low", PriorityLow, false},
+		{"High", PriorityHigh, false},
+		{"critical", PriorityCritical, false},
+		{"-3", -3, false},
+		{"42", 42, false},
+		{"urgent", 0, true},
+		{"1.5", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePriority(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParsePriority(%q) = %d, %v; want %d, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func jobIDs(jobs []*models.Job) []string {
+	ids := make([]string, 0, len(jobs))
+	for _, j := range jobs {
+		ids = append(ids, j.ID)
+	}
+	return ids
+}
@@ -0,0 +1,130 @@
+package job
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+	"github.com/chrlesur/orchestrator/pkg/logger"
+)
+
+// RecoveryReport résume la reprise des jobs qui étaient en cours lors du dernier arrêt
+type RecoveryReport struct {
+	Time     time.Time
+	Requeued []string
+	Lost     []string
+}
+
+// ValidateRecoveryPolicy vérifie une politique de reprise
+func ValidateRecoveryPolicy(policy models.RecoveryPolicy) error {
+	switch policy {
+	case "", models.RecoveryRequeue, models.RecoveryLost:
+		return nil
+	}
+	return fmt.Errorf("unknown recovery policy: %s", policy)
+}
+
+// ResetRuntime efface l'état d'exécution d'un job afin qu'il puisse être exécuté de nouveau.
+// Le numéro de la dernière tentative est conservé : les suivantes ne remplacent ni son
+// historique ni ses fichiers de log
+func ResetRuntime(j *models.Job) {
+	j.Status = models.JobStatusPending
+	j.Result = ""
+	j.OutputTruncated = false
+	j.OutputPath = ""
+	j.ParsedResult = nil
+	j.ResolvedArgs = nil
+	j.Progress = 0
+	j.StatusMessage = ""
+	j.Outputs = nil
+	j.Error = nil
+	j.ExitCode = 0
+	j.FailureReason = ""
+	j.QueuedAt = time.Time{}
+	j.StartTime = time.Time{}
+	j.EndTime = time.Time{}
+	j.RetryCount = 0
+}
+
+// recoverJobs traite les jobs restés en cours lors de l'arrêt précédent. Un job est remis
+// en file si sa politique le demande et qu'il lui reste des tentatives : la tentative
+// interrompue est comptée. Les autres, ainsi que les étapes de pipeline, sont marqués perdus
+func (m *Manager) recoverJobs(jobs []*models.Job) {
+	m.recovery = RecoveryReport{Time: time.Now()}
+
+	for _, j := range jobs {
+		if j.Status != models.JobStatusRunning {
+			continue
+		}
+		m.closeInterruptedAttempt(j)
+
+		policy := j.RecoveryPolicy
+		if policy == "" {
+			policy = m.settings.RecoveryPolicy
+		}
+
+		if policy == models.RecoveryRequeue && j.PipelineID == "" && j.RetryCount < j.MaxRetries {
+			j.RetryCount++
+			j.Status = models.JobStatusPending
+			m.recovery.Requeued = append(m.recovery.Requeued, j.ID)
+			logger.Warning(fmt.Sprintf("Job %s was running at shutdown and is requeued", j.ID))
+		} else {
+			j.Status = models.JobStatusLost
+			j.Error = fmt.Errorf("job was running when the orchestrator stopped")
+			j.FailureReason = models.FailureInterrupted
+			j.EndTime = m.recovery.Time
+			m.recovery.Lost = append(m.recovery.Lost, j.ID)
+			logger.Warning(fmt.Sprintf("Job %s was running at shutdown and is marked as lost", j.ID))
+		}
+		if err := m.store.SaveJob(j); err != nil {
+			logger.Error(fmt.Sprintf("Failed to save recovered job %s: %v", j.ID, err))
+		}
+	}
+
+	if len(m.recovery.Requeued) > 0 || len(m.recovery.Lost) > 0 {
+		logger.Info(fmt.Sprintf("Job recovery: %d requeued, %d lost", len(m.recovery.Requeued), len(m.recovery.Lost)))
+	}
+}
+
+// closeInterruptedAttempt termine l'enregistrement de la tentative interrompue par l'arrêt.
+// Le job, enregistré avant le début de cette tentative, reprend son numéro
+func (m *Manager) closeInterruptedAttempt(j *models.Job) {
+	attempts, err := m.store.GetAttempts(j.ID)
+	if err != nil {
+		return
+	}
+	for _, a := range attempts {
+		if a.Number > j.Attempt {
+			j.Attempt = a.Number
+		}
+		if a.EndTime.IsZero() {
+			a.EndTime = time.Now()
+			a.Error = "interrupted by an orchestrator shutdown"
+			a.FailureReason = models.FailureInterrupted
+			if err := m.store.SaveAttempt(a); err != nil {
+				logger.Error(fmt.Sprintf("Failed to save attempt %d of job %s: %v", a.Number, j.ID, err))
+			}
+		}
+	}
+}
+
+// Recovery retourne le résumé de la reprise effectuée au démarrage
+func (m *Manager) Recovery() RecoveryReport {
+	return m.recovery
+}
+
+// ResetStep prépare une étape de pipeline à une nouvelle exécution et retourne l'instance
+// suivie par le gestionnaire, qui remplace la copie chargée avec le pipeline
+func (m *Manager) ResetStep(step *models.Job) *models.Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if tracked, exists := m.jobs[step.ID]; exists {
+		step = tracked
+	}
+	ResetRuntime(step)
+	if err := m.store.SaveJob(step); err != nil {
+		logger.Error(fmt.Sprintf("Failed to save job %s: %v", step.ID, err))
+	}
+	return step
+}
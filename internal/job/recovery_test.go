@@ -0,0 +1,129 @@
+package job
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chrlesur/orchestrator/internal/db"
+	"github.com/chrlesur/orchestrator/internal/models"
+)
+
+// interruptJob soumet un job, arrête brutalement l'orchestrateur pendant son exécution en
+// fermant la base, puis rouvre celle-ci avec un nouveau gestionnaire
+func interruptJob(t *testing.T, settings Settings, command string, args []string) (*Manager, *models.Job) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "orchestrator.db")
+	settings.DefaultTimeout = time.Minute
+	settings.LogDir = filepath.Join(dir, "logs")
+	settings.WorkspaceDir = filepath.Join(dir, "workspaces")
+
+	store, err := db.NewStore(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(1, store, settings, nil)
+	j := m.PrepareJob("interrupted", command, args, "")
+	j.MaxRetries = 1
+	if err := m.SubmitJob(j); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		attempts, err := store.GetAttempts(j.ID)
+		return err == nil && len(attempts) == 1
+	})
+
+	stored, err := store.GetJob(j.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.JobStatusRunning {
+		t.Fatalf("stored status while running = %s, want %s", stored.Status, models.JobStatusRunning)
+	}
+
+	store.Close()
+	t.Cleanup(func() { m.CancelJob(j.ID) })
+
+	store, err = db.NewStore(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(store.Close)
+	return NewManager(1, store, settings, nil), j
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before the deadline")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestRecoveryMarksInterruptedJobLost(t *testing.T) {
+	recovered, j := interruptJob(t, Settings{RecoveryPolicy: models.RecoveryLost}, "sleep", []string{"30"})
+	defer recovered.Shutdown()
+
+	got, err := recovered.GetJob(j.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.JobStatusLost || got.FailureReason != models.FailureInterrupted {
+		t.Fatalf("recovered job = %s (%s), want %s (%s)", got.Status, got.FailureReason, models.JobStatusLost, models.FailureInterrupted)
+	}
+	if report := recovered.Recovery(); len(report.Lost) != 1 || report.Lost[0] != j.ID {
+		t.Fatalf("recovery report lost = %v, want [%s]", report.Lost, j.ID)
+	}
+
+	attempts, err := recovered.GetAttempts(j.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attempts) != 1 || attempts[0].EndTime.IsZero() || attempts[0].FailureReason != models.FailureInterrupted {
+		t.Fatalf("interrupted attempt was not closed: %+v", attempts)
+	}
+}
+
+func TestRecoveryRequeuesInterruptedJob(t *testing.T) {
+	// La première exécution crée le marqueur et reste en cours, la suivante se termine aussitôt
+	script := `test -f "$ORCH_WORKSPACE/marker" || { touch "$ORCH_WORKSPACE/marker"; sleep 30; }`
+	recovered, j := interruptJob(t, Settings{RecoveryPolicy: models.RecoveryRequeue}, "sh", []string{"-c", script})
+	defer recovered.Shutdown()
+
+	if report := recovered.Recovery(); len(report.Requeued) != 1 || report.Requeued[0] != j.ID {
+		t.Fatalf("recovery report requeued = %v, want [%s]", report.Requeued, j.ID)
+	}
+	waitFor(t, func() bool {
+		got, err := recovered.store.GetJob(j.ID)
+		return err == nil && got.Status == models.JobStatusCompleted
+	})
+
+	attempts, err := recovered.GetAttempts(j.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attempts) != 2 {
+		t.Fatalf("got %d attempts, want 2", len(attempts))
+	}
+	if attempts[0].FailureReason != models.FailureInterrupted || attempts[1].ExitCode != 0 || attempts[1].EndTime.IsZero() {
+		t.Fatalf("unexpected attempts after requeue: %+v %+v", attempts[0], attempts[1])
+	}
+}
+
+func TestResetRuntimeKeepsAttemptNumbers(t *testing.T) {
+	j := NewJob("reset", "true", nil, time.Minute, 0)
+	if err := Execute(j, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ResetRuntime(j)
+	if err := Execute(j, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if j.Attempt != 2 {
+		t.Fatalf("attempt after a reset = %d, want 2", j.Attempt)
+	}
+}
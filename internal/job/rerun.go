@@ -0,0 +1,74 @@
+package job
+
+import (
+	"fmt"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+	"github.com/chrlesur/orchestrator/pkg/logger"
+	"github.com/chrlesur/orchestrator/pkg/utils"
+)
+
+// CopyJob prépare, sans le soumettre, un nouveau job reprenant la définition du job id.
+// La copie est liée à l'original par ParentID et s'exécute hors de tout pipeline. Seuls les
+// champs de définition sont lus, l'état d'exécution de l'original pouvant évoluer en parallèle
+func (m *Manager) CopyJob(id string) (*models.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	original, exists := m.jobs[id]
+	if !exists {
+		return nil, fmt.Errorf("job with ID %s not found", id)
+	}
+
+	copied := &models.Job{
+		ID:               utils.GenerateID(8),
+		Name:             original.Name,
+		Command:          original.Command,
+		Args:             append([]string(nil), original.Args...),
+		Script:           original.Script,
+		Interpreter:      original.Interpreter,
+		WorkingDir:       original.WorkingDir,
+		Stdin:            original.Stdin,
+		RunAsUser:        original.RunAsUser,
+		RunAsGroup:       original.RunAsGroup,
+		Isolation:        original.Isolation,
+		Host:             original.Host,
+		Limits:           original.Limits,
+		Locks:            append([]models.ResourceLock(nil), original.Locks...),
+		DependsOn:        append([]string(nil), original.DependsOn...),
+		DependencyPolicy: original.DependencyPolicy,
+		RecoveryPolicy:   original.RecoveryPolicy,
+		Priority:         original.Priority,
+		Timeout:          original.Timeout,
+		GracePeriod:      original.GracePeriod,
+		MaxRetries:       original.MaxRetries,
+		RetryPolicy:      original.RetryPolicy,
+		Success:          original.Success,
+		ResultFormat:     original.ResultFormat,
+		StepName:         original.StepName,
+		ArtifactGlobs:    append([]string(nil), original.ArtifactGlobs...),
+		Status:           models.JobStatusPending,
+		PluginName:       original.PluginName,
+		ParentID:         id,
+	}
+	if original.Env != nil {
+		copied.Env = make(map[string]string, len(original.Env))
+		for key, value := range original.Env {
+			copied.Env[key] = value
+		}
+	}
+	return copied, nil
+}
+
+// RerunJob soumet une nouvelle exécution du job id avec sa définition d'origine
+func (m *Manager) RerunJob(id string) (*models.Job, error) {
+	j, err := m.CopyJob(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.SubmitJob(j); err != nil {
+		return nil, err
+	}
+	logger.Info(fmt.Sprintf("Job %s submitted as a rerun of job %s", j.ID, id))
+	return j, nil
+}
@@ -0,0 +1,88 @@
+package job
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+	"github.com/chrlesur/orchestrator/pkg/utils"
+)
+
+// ValidateResultFormat vérifie le format de résultat d'un job
+func ValidateResultFormat(format models.ResultFormat) error {
+	switch format {
+	case "", models.ResultFormatText, models.ResultFormatJSON, models.ResultFormatKV:
+		return nil
+	}
+	return fmt.Errorf("unknown result format: %s", format)
+}
+
+// ParseResult convertit la sortie d'un job selon son format de résultat. Le format kv
+// attend des paires clé=valeur séparées par des virgules ou des retours à la ligne
+func ParseResult(format models.ResultFormat, output string) (interface{}, error) {
+	switch format {
+	case models.ResultFormatJSON:
+		var result interface{}
+		if err := json.Unmarshal([]byte(output), &result); err != nil {
+			return nil, fmt.Errorf("result is not valid JSON: %v", err)
+		}
+		return result, nil
+	case models.ResultFormatKV:
+		var pairs []string
+		for _, line := range strings.Split(output, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				pairs = append(pairs, line)
+			}
+		}
+		if len(pairs) == 0 {
+			return map[string]string{}, nil
+		}
+		result, err := utils.ParseKeyValuePairs(strings.Join(pairs, ","))
+		if err != nil {
+			return nil, fmt.Errorf("result is not a list of key=value pairs: %v", err)
+		}
+		return result, nil
+	}
+	return nil, nil
+}
+
+// parseResult renseigne le résultat structuré d'un job terminé avec succès à partir de sa
+// sortie, débarrassée des directives
+func parseResult(j *models.Job, output string, truncated bool) error {
+	if j.ResultFormat == "" || j.ResultFormat == models.ResultFormatText {
+		return nil
+	}
+	if truncated {
+		return fmt.Errorf("could not parse result: the output exceeds the maximum output size")
+	}
+	result, err := ParseResult(j.ResultFormat, output)
+	if err != nil {
+		return err
+	}
+	j.ParsedResult = result
+	return nil
+}
+
+// ResolveArgs substitue dans les arguments d'une étape les valeurs du contexte de son
+// pipeline, par exemple {{.steps.fetch.outputs.count}}. Les arguments d'origine sont conservés
+func ResolveArgs(j *models.Job, context map[string]interface{}) error {
+	j.ResolvedArgs = nil
+	templated := false
+	for _, arg := range j.Args {
+		if strings.Contains(arg, "{{") {
+			templated = true
+			break
+		}
+	}
+	if !templated {
+		return nil
+	}
+
+	args, err := renderArgs(j.Args, context)
+	if err != nil {
+		return err
+	}
+	j.ResolvedArgs = args
+	return nil
+}
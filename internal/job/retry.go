@@ -0,0 +1,98 @@
+package job
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"time"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+	"github.com/chrlesur/orchestrator/pkg/logger"
+)
+
+// Délai initial utilisé lorsque la politique n'en précise pas (2s, 4s, 8s... en exponentiel)
+const defaultRetryDelay = 2 * time.Second
+
+// ValidateRetryPolicy vérifie la cohérence d'une politique de nouvelles tentatives
+func ValidateRetryPolicy(p *models.RetryPolicy) error {
+	if p == nil {
+		return nil
+	}
+	switch p.Backoff {
+	case "", models.BackoffConstant, models.BackoffLinear, models.BackoffExponential:
+	default:
+		return fmt.Errorf("unknown backoff strategy %q", p.Backoff)
+	}
+	if p.InitialDelay < 0 || p.MaxDelay < 0 {
+		return fmt.Errorf("retry delays must not be negative")
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return fmt.Errorf("retry jitter must be between 0 and 1")
+	}
+	for _, pattern := range p.RetryOnOutput {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid retry output pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// retryDelay calcule le délai d'attente avant la nouvelle tentative numéro n (à partir de 1)
+func retryDelay(p *models.RetryPolicy, n int) time.Duration {
+	delay := p.InitialDelay
+	if delay == 0 {
+		delay = defaultRetryDelay
+	}
+
+	switch p.Backoff {
+	case models.BackoffConstant:
+	case models.BackoffLinear:
+		delay *= time.Duration(n)
+	default:
+		for i := 1; i < n && (p.MaxDelay == 0 || delay < p.MaxDelay); i++ {
+			delay *= 2
+		}
+	}
+
+	if p.Jitter > 0 {
+		delay += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(delay))
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// shouldRetry indique si l'échec d'une tentative justifie de relancer le job
+func shouldRetry(p *models.RetryPolicy, attempt *models.JobAttempt, output attemptOutput) bool {
+	if attempt.TimedOut {
+		return !p.NoRetryOnTimeout
+	}
+
+	// Sans condition, tout échec est relancé
+	if len(p.RetryOnExitCodes) == 0 && len(p.RetryOnOutput) == 0 {
+		return true
+	}
+
+	for _, code := range p.RetryOnExitCodes {
+		if attempt.ExitCode == code {
+			return true
+		}
+	}
+	// Une sortie qui ne peut pas être examinée en entier ne justifie pas de nouvelle tentative
+	for _, pattern := range p.RetryOnOutput {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			continue
+		}
+		matched, err := output.match(re)
+		if err != nil {
+			logger.Warning(fmt.Sprintf("Job %s: retry condition %q not checked: %v", attempt.JobID, pattern, err))
+			continue
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package job
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy models.RetryPolicy
+		want   []time.Duration // Délais des tentatives 1, 2, 3...
+	}{
+		{"default exponential", models.RetryPolicy{}, []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second}},
+		{"constant", models.RetryPolicy{Backoff: models.BackoffConstant, InitialDelay: time.Second}, []time.Duration{time.Second, time.Second, time.Second}},
+		{"linear", models.RetryPolicy{Backoff: models.BackoffLinear, InitialDelay: time.Second}, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}},
+		{"exponential capped", models.RetryPolicy{Backoff: models.BackoffExponential, InitialDelay: time.Second, MaxDelay: 5 * time.Second}, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}},
+		{"linear capped", models.RetryPolicy{Backoff: models.BackoffLinear, InitialDelay: 2 * time.Second, MaxDelay: 3 * time.Second}, []time.Duration{2 * time.Second, 3 * time.Second, 3 * time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, want := range tt.want {
+				if got := retryDelay(&tt.policy, i+1); got != want {
+					t.Fatalf("delay of retry %d = %v, want %v", i+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryDelayJitter(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   models.RetryPolicy
+		min, max time.Duration
+	}{
+		{"within the jitter", models.RetryPolicy{Backoff: models.BackoffConstant, InitialDelay: 10 * time.Second, Jitter: 0.2}, 8 * time.Second, 12 * time.Second},
+		{"capped after the jitter", models.RetryPolicy{Backoff: models.BackoffConstant, InitialDelay: 10 * time.Second, MaxDelay: 10 * time.Second, Jitter: 0.5}, 5 * time.Second, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spread := false
+			for i := 0; i < 200; i++ {
+				got := retryDelay(&tt.policy, 1)
+				if got < tt.min || got > tt.max {
+					t.Fatalf("delay = %v, want between %v and %v", got, tt.min, tt.max)
+				}
+				spread = spread || got != tt.policy.InitialDelay
+			}
+			if !spread {
+				t.Fatal("jitter never changed the delay")
+			}
+		})
+	}
+}
+
+func TestValidateRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  *models.RetryPolicy
+		wantErr bool
+	}{
+		{"no policy", nil, false},
+		{"valid", &models.RetryPolicy{Backoff: models.BackoffLinear, InitialDelay: time.Second, Jitter: 0.5, RetryOnOutput: []string{"timeout"}}, false},
+		{"unknown backoff", &models.RetryPolicy{Backoff: "random"}, true},
+		{"negative delay", &models.RetryPolicy{InitialDelay: -time.Second}, true},
+		{"negative max delay", &models.RetryPolicy{MaxDelay: -time.Second}, true},
+		{"jitter above 1", &models.RetryPolicy{Jitter: 1.5}, true},
+		{"negative jitter", &models.RetryPolicy{Jitter: -0.1}, true},
+		{"invalid output pattern", &models.RetryPolicy{RetryOnOutput: []string{"("}}, true},
+	}
+	for _, tt := range tests {
+		if err := ValidateRetryPolicy(tt.policy); (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestShouldRetry(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  models.RetryPolicy
+		attempt models.JobAttempt
+		want    bool
+	}{
+		{"any failure", models.RetryPolicy{}, models.JobAttempt{ExitCode: 1}, true},
+		{"listed exit code", models.RetryPolicy{RetryOnExitCodes: []int{75, 2}}, models.JobAttempt{ExitCode: 2}, true},
+		{"other exit code", models.RetryPolicy{RetryOnExitCodes: []int{75}}, models.JobAttempt{ExitCode: 1}, false},
+		{"timeout", models.RetryPolicy{RetryOnExitCodes: []int{75}}, models.JobAttempt{TimedOut: true}, true},
+		{"timeout not retried", models.RetryPolicy{NoRetryOnTimeout: true}, models.JobAttempt{TimedOut: true}, false},
+	}
+	for _, tt := range tests {
+		if got := shouldRetry(&tt.policy, &tt.attempt, attemptOutput{}); got != tt.want {
+			t.Errorf("%s: shouldRetry = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
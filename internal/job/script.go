@@ -0,0 +1,60 @@
+package job
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+)
+
+// Interpréteur utilisé lorsqu'un job script n'en précise pas
+const defaultInterpreter = "sh"
+
+// IsScript indique si le job exécute un script inline plutôt qu'une commande
+func IsScript(j *models.Job) bool {
+	return j.Script != ""
+}
+
+// commandLine retourne la commande à lancer pour une tentative. Pour un job script,
+// le corps du script est écrit dans un fichier temporaire passé à l'interpréteur ;
+// la fonction de nettoyage retournée supprime ce fichier
+func commandLine(j *models.Job) (string, []string, func(), error) {
+	args := jobArgs(j)
+	if !IsScript(j) {
+		return j.Command, args, func() {}, nil
+	}
+
+	f, err := ioutil.TempFile("", fmt.Sprintf("orchestrator-%s-*.script", j.ID))
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("could not create script file: %v", err)
+	}
+	cleanup := func() { os.Remove(f.Name()) }
+
+	if _, err := f.WriteString(j.Script); err != nil {
+		f.Close()
+		cleanup()
+		return "", nil, nil, fmt.Errorf("could not write script file: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		cleanup()
+		return "", nil, nil, fmt.Errorf("could not write script file: %v", err)
+	}
+
+	return interpreter(j), append([]string{f.Name()}, args...), cleanup, nil
+}
+
+// jobArgs retourne les arguments d'une tentative, après substitution du contexte du pipeline
+func jobArgs(j *models.Job) []string {
+	if j.ResolvedArgs != nil {
+		return j.ResolvedArgs
+	}
+	return j.Args
+}
+
+func interpreter(j *models.Job) string {
+	if j.Interpreter == "" {
+		return defaultInterpreter
+	}
+	return j.Interpreter
+}
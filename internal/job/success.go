@@ -0,0 +1,77 @@
+package job
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+)
+
+// ValidateSuccessCriteria vérifie que les expressions régulières des critères de succès compilent
+func ValidateSuccessCriteria(c *models.SuccessCriteria) error {
+	if c == nil {
+		return nil
+	}
+	for _, pattern := range append(append([]string{}, c.StdoutMustMatch...), c.StdoutMustNotMatch...) {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid success pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// checkSuccess applique les critères de succès au code de sortie et à la sortie standard
+// complète d'une tentative. Sans critère, seul le code de sortie 0 est accepté
+func checkSuccess(c *models.SuccessCriteria, exitCode int, stdout streamOutput) error {
+	if c == nil {
+		c = &models.SuccessCriteria{}
+	}
+
+	accepted := c.ExitCodes
+	if len(accepted) == 0 {
+		accepted = []int{0}
+	}
+	ok := false
+	for _, code := range accepted {
+		if exitCode == code {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return fmt.Errorf("exit code %d is not accepted", exitCode)
+	}
+
+	for _, pattern := range c.StdoutMustMatch {
+		matched, err := matchOutput(pattern, stdout)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			return fmt.Errorf("stdout does not match %q", pattern)
+		}
+	}
+	for _, pattern := range c.StdoutMustNotMatch {
+		matched, err := matchOutput(pattern, stdout)
+		if err != nil {
+			return err
+		}
+		if matched {
+			return fmt.Errorf("stdout matches forbidden pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// matchOutput applique un motif des critères de succès à stdout
+func matchOutput(pattern string, stdout streamOutput) (bool, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, fmt.Errorf("invalid success pattern %q: %v", pattern, err)
+	}
+	matched, err := stdout.match(re)
+	if err != nil {
+		return false, fmt.Errorf("could not check stdout against %q: %v", pattern, err)
+	}
+	return matched, nil
+}
@@ -0,0 +1,91 @@
+package job
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+)
+
+func TestCheckSuccess(t *testing.T) {
+	full := filepath.Join(t.TempDir(), "stdout")
+	if err := ioutil.WriteFile(full, []byte(strings.Repeat("x", 1000)+"\nDONE\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	done := streamOutput{preview: "building\nDONE\n"}
+	failed := streamOutput{preview: "building\nERROR: disk full\n"}
+	truncated := streamOutput{preview: "xxxx", truncated: true, path: full}
+	lost := streamOutput{preview: "xxxx", truncated: true}
+
+	tests := []struct {
+		name     string
+		criteria *models.SuccessCriteria
+		exitCode int
+		stdout   streamOutput
+		wantErr  bool
+	}{
+		{"no criteria, exit 0", nil, 0, done, false},
+		{"no criteria, exit 1", nil, 1, done, true},
+		{"accepted exit code", &models.SuccessCriteria{ExitCodes: []int{0, 3}}, 3, done, false},
+		{"exit 0 not listed", &models.SuccessCriteria{ExitCodes: []int{3}}, 0, done, true},
+		{"required pattern", &models.SuccessCriteria{StdoutMustMatch: []string{`(?m)^DONE$`}}, 0, done, false},
+		{"required pattern missing", &models.SuccessCriteria{StdoutMustMatch: []string{`(?m)^DONE$`}}, 0, failed, true},
+		{"forbidden pattern", &models.SuccessCriteria{StdoutMustNotMatch: []string{`ERROR`}}, 0, failed, true},
+		{"forbidden pattern absent", &models.SuccessCriteria{StdoutMustNotMatch: []string{`ERROR`}}, 0, done, false},
+		{"exit code checked first", &models.SuccessCriteria{StdoutMustMatch: []string{`DONE`}}, 1, done, true},
+		{"pattern beyond the preview", &models.SuccessCriteria{StdoutMustMatch: []string{`DONE`}}, 0, truncated, false},
+		{"forbidden pattern beyond the preview", &models.SuccessCriteria{StdoutMustNotMatch: []string{`DONE`}}, 0, truncated, true},
+		{"truncated output not kept", &models.SuccessCriteria{StdoutMustNotMatch: []string{`ERROR`}}, 0, lost, true},
+		{"truncated output without pattern", &models.SuccessCriteria{ExitCodes: []int{0}}, 0, lost, false},
+	}
+	for _, tt := range tests {
+		if err := checkSuccess(tt.criteria, tt.exitCode, tt.stdout); (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateSuccessCriteria(t *testing.T) {
+	tests := []struct {
+		name     string
+		criteria *models.SuccessCriteria
+		wantErr  bool
+	}{
+		{"no criteria", nil, false},
+		{"valid patterns", &models.SuccessCriteria{StdoutMustMatch: []string{`^ok`}, StdoutMustNotMatch: []string{`fail(ed)?`}}, false},
+		{"invalid required pattern", &models.SuccessCriteria{StdoutMustMatch: []string{`[`}}, true},
+		{"invalid forbidden pattern", &models.SuccessCriteria{StdoutMustNotMatch: []string{`(`}}, true},
+	}
+	for _, tt := range tests {
+		if err := ValidateSuccessCriteria(tt.criteria); (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestShouldRetryOnOutput(t *testing.T) {
+	full := filepath.Join(t.TempDir(), "stderr")
+	if err := ioutil.WriteFile(full, []byte(strings.Repeat("x", 1000)+"\nconnection reset\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	policy := &models.RetryPolicy{RetryOnOutput: []string{`connection reset`}}
+
+	tests := []struct {
+		name   string
+		output attemptOutput
+		want   bool
+	}{
+		{"match on stdout", attemptOutput{stdout: streamOutput{preview: "connection reset"}}, true},
+		{"match on stderr", attemptOutput{stderr: streamOutput{preview: "connection reset"}}, true},
+		{"no match", attemptOutput{stdout: streamOutput{preview: "ok"}, stderr: streamOutput{preview: "warning"}}, false},
+		{"match beyond the preview", attemptOutput{stderr: streamOutput{preview: "xxxx", truncated: true, path: full}}, true},
+		{"truncated output not kept", attemptOutput{stderr: streamOutput{preview: "xxxx", truncated: true}}, false},
+	}
+	for _, tt := range tests {
+		if got := shouldRetry(policy, &models.JobAttempt{ExitCode: 1}, tt.output); got != tt.want {
+			t.Errorf("%s: shouldRetry = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
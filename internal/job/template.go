@@ -0,0 +1,197 @@
+package job
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+)
+
+var templateParamName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateTemplate vérifie un modèle de job, ses paramètres et les emplacements de ses arguments
+func ValidateTemplate(t *models.JobTemplate) error {
+	if t.Name == "" {
+		return fmt.Errorf("template name must not be empty")
+	}
+	if (t.Command == "") == (t.PluginName == "") {
+		return fmt.Errorf("template must define either a command or a plugin")
+	}
+	if t.Timeout < 0 {
+		return fmt.Errorf("template timeout must not be negative")
+	}
+	if t.MaxRetries != nil && *t.MaxRetries < 0 {
+		return fmt.Errorf("template max retries must not be negative")
+	}
+
+	sample := make(map[string]interface{})
+	for _, p := range t.Params {
+		if !templateParamName.MatchString(p.Name) {
+			return fmt.Errorf("invalid template parameter name: %q", p.Name)
+		}
+		if _, exists := sample[p.Name]; exists {
+			return fmt.Errorf("template parameter %s is declared more than once", p.Name)
+		}
+		raw := p.Default
+		if raw == "" {
+			raw = zeroParamValue(p.Type)
+		}
+		value, err := parseParamValue(p, raw)
+		if err != nil {
+			return fmt.Errorf("invalid default of template parameter %s: %v", p.Name, err)
+		}
+		sample[p.Name] = value
+	}
+
+	// Un rendu avec des valeurs d'exemple détecte les emplacements non déclarés
+	if _, err := renderArgs(t.Args, sample); err != nil {
+		return err
+	}
+	return nil
+}
+
+// parseParamValue convertit la valeur brute d'un paramètre selon son type déclaré
+func parseParamValue(p models.TemplateParam, raw string) (interface{}, error) {
+	switch p.Type {
+	case "", models.ParamString:
+		return raw, nil
+	case models.ParamInt:
+		return strconv.ParseInt(raw, 10, 64)
+	case models.ParamFloat:
+		return strconv.ParseFloat(raw, 64)
+	case models.ParamBool:
+		return strconv.ParseBool(raw)
+	}
+	return nil, fmt.Errorf("unknown parameter type: %s", p.Type)
+}
+
+func zeroParamValue(paramType string) string {
+	switch paramType {
+	case models.ParamInt, models.ParamFloat:
+		return "0"
+	case models.ParamBool:
+		return "false"
+	}
+	return ""
+}
+
+// resolveParams valide les paramètres fournis et complète les valeurs par défaut
+func resolveParams(t *models.JobTemplate, params map[string]string) (map[string]interface{}, error) {
+	declared := make(map[string]bool)
+	values := make(map[string]interface{})
+	for _, p := range t.Params {
+		declared[p.Name] = true
+
+		raw, provided := params[p.Name]
+		if !provided {
+			if p.Required {
+				return nil, fmt.Errorf("missing required parameter %s", p.Name)
+			}
+			raw = p.Default
+			if raw == "" {
+				raw = zeroParamValue(p.Type)
+			}
+		}
+		value, err := parseParamValue(p, raw)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s must be of type %s: %q", p.Name, p.Type, raw)
+		}
+		values[p.Name] = value
+	}
+
+	for name := range params {
+		if !declared[name] {
+			return nil, fmt.Errorf("unknown parameter %s", name)
+		}
+	}
+	return values, nil
+}
+
+func renderArgs(args []string, values map[string]interface{}) ([]string, error) {
+	rendered := make([]string, 0, len(args))
+	for i, arg := range args {
+		tmpl, err := template.New(fmt.Sprintf("arg%d", i)).Option("missingkey=error").Parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template argument %q: %v", arg, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, values); err != nil {
+			return nil, fmt.Errorf("could not render argument %q: %v", arg, err)
+		}
+		rendered = append(rendered, buf.String())
+	}
+	return rendered, nil
+}
+
+// SaveTemplate valide et enregistre un modèle de job, en remplaçant celui de même nom
+func (m *Manager) SaveTemplate(t *models.JobTemplate) error {
+	if err := ValidateTemplate(t); err != nil {
+		return err
+	}
+	for i := range t.Params {
+		if t.Params[i].Type == "" {
+			t.Params[i].Type = models.ParamString
+		}
+	}
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	return m.store.SaveTemplate(t)
+}
+
+func (m *Manager) GetTemplate(name string) (*models.JobTemplate, error) {
+	return m.store.GetTemplate(name)
+}
+
+func (m *Manager) GetTemplates() ([]*models.JobTemplate, error) {
+	return m.store.GetAllTemplates()
+}
+
+func (m *Manager) DeleteTemplate(name string) error {
+	if _, err := m.store.GetTemplate(name); err != nil {
+		return err
+	}
+	return m.store.DeleteTemplate(name)
+}
+
+// InstantiateTemplate prépare, sans le soumettre, un job à partir d'un modèle et de ses paramètres
+func (m *Manager) InstantiateTemplate(name string, params map[string]string) (*models.Job, error) {
+	t, err := m.store.GetTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := resolveParams(t, params)
+	if err != nil {
+		return nil, err
+	}
+	args, err := renderArgs(t.Args, values)
+	if err != nil {
+		return nil, err
+	}
+
+	j := m.PrepareJob(t.Name, t.Command, args, t.PluginName)
+	if t.Timeout > 0 {
+		j.Timeout = t.Timeout
+	}
+	if t.MaxRetries != nil {
+		j.MaxRetries = *t.MaxRetries
+	}
+	return j, nil
+}
+
+// RunTemplate instancie un modèle et soumet le job obtenu
+func (m *Manager) RunTemplate(name string, params map[string]string) (*models.Job, error) {
+	j, err := m.InstantiateTemplate(name, params)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.SubmitJob(j); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
@@ -0,0 +1,98 @@
+package job
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+)
+
+func TestValidateTemplate(t *testing.T) {
+	negative := -1
+	tests := []struct {
+		name     string
+		template models.JobTemplate
+		wantErr  bool
+	}{
+		{"valid", models.JobTemplate{Name: "sync", Command: "sync", Args: []string{"{{.tenant}}"}, Params: []models.TemplateParam{{Name: "tenant"}}}, false},
+		{"no name", models.JobTemplate{Command: "sync"}, true},
+		{"command and plugin", models.JobTemplate{Name: "sync", Command: "sync", PluginName: "p"}, true},
+		{"neither command nor plugin", models.JobTemplate{Name: "sync"}, true},
+		{"negative timeout", models.JobTemplate{Name: "sync", Command: "sync", Timeout: -time.Second}, true},
+		{"negative max retries", models.JobTemplate{Name: "sync", Command: "sync", MaxRetries: &negative}, true},
+		{"invalid parameter name", models.JobTemplate{Name: "sync", Command: "sync", Params: []models.TemplateParam{{Name: "batch-size"}}}, true},
+		{"parameter declared twice", models.JobTemplate{Name: "sync", Command: "sync", Params: []models.TemplateParam{{Name: "a"}, {Name: "a"}}}, true},
+		{"unknown parameter type", models.JobTemplate{Name: "sync", Command: "sync", Params: []models.TemplateParam{{Name: "a", Type: "date"}}}, true},
+		{"default of the wrong type", models.JobTemplate{Name: "sync", Command: "sync", Params: []models.TemplateParam{{Name: "a", Type: models.ParamInt, Default: "ten"}}}, true},
+		{"undeclared placeholder", models.JobTemplate{Name: "sync", Command: "sync", Args: []string{"{{.tenant}}"}}, true},
+		{"invalid placeholder", models.JobTemplate{Name: "sync", Command: "sync", Args: []string{"{{.tenant"}}, true},
+	}
+	for _, tt := range tests {
+		if err := ValidateTemplate(&tt.template); (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestInstantiateTemplate(t *testing.T) {
+	m := testManager(t, Settings{MaxRetries: 3})
+	params := []models.TemplateParam{
+		{Name: "tenant", Type: models.ParamString, Required: true},
+		{Name: "batch", Type: models.ParamInt, Default: "100"},
+		{Name: "ratio", Type: models.ParamFloat},
+		{Name: "dry", Type: models.ParamBool},
+	}
+	args := []string{"--tenant", "{{.tenant}}", "--batch", "{{.batch}}", "--ratio", "{{.ratio}}", "--dry={{.dry}}"}
+	zero, two := 0, 2
+	templates := []*models.JobTemplate{
+		{Name: "defaults", Command: "sync", Args: args, Params: params},
+		{Name: "overrides", Command: "sync", Args: args, Params: params, Timeout: 10 * time.Minute, MaxRetries: &two},
+		{Name: "no-retry", Command: "sync", MaxRetries: &zero},
+	}
+	for _, tmpl := range templates {
+		if err := m.SaveTemplate(tmpl); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		template    string
+		params      map[string]string
+		wantArgs    []string
+		wantTimeout time.Duration
+		wantRetries int
+		wantErr     bool
+	}{
+		{"default values", "defaults", map[string]string{"tenant": "acme"},
+			[]string{"--tenant", "acme", "--batch", "100", "--ratio", "0", "--dry=false"}, time.Minute, 3, false},
+		{"typed values", "defaults", map[string]string{"tenant": "acme", "batch": "-5", "ratio": "0.25", "dry": "true"},
+			[]string{"--tenant", "acme", "--batch", "-5", "--ratio", "0.25", "--dry=true"}, time.Minute, 3, false},
+		{"template settings", "overrides", map[string]string{"tenant": "acme"},
+			[]string{"--tenant", "acme", "--batch", "100", "--ratio", "0", "--dry=false"}, 10 * time.Minute, 2, false},
+		{"retries disabled", "no-retry", nil, []string{}, time.Minute, 0, false},
+		{"missing required parameter", "defaults", nil, nil, 0, 0, true},
+		{"unknown parameter", "defaults", map[string]string{"tenant": "acme", "region": "eu"}, nil, 0, 0, true},
+		{"invalid int", "defaults", map[string]string{"tenant": "acme", "batch": "1.5"}, nil, 0, 0, true},
+		{"invalid bool", "defaults", map[string]string{"tenant": "acme", "dry": "maybe"}, nil, 0, 0, true},
+		{"unknown template", "missing", nil, nil, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j, err := m.InstantiateTemplate(tt.template, tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(j.Args, tt.wantArgs) {
+				t.Fatalf("args = %q, want %q", j.Args, tt.wantArgs)
+			}
+			if j.Timeout != tt.wantTimeout || j.MaxRetries != tt.wantRetries {
+				t.Fatalf("timeout = %v, max retries = %d; want %v and %d", j.Timeout, j.MaxRetries, tt.wantTimeout, tt.wantRetries)
+			}
+		})
+	}
+}
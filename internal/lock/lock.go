@@ -0,0 +1,295 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+)
+
+// Types de détenteurs d'un verrou
+const (
+	OwnerJob      = "job"
+	OwnerPipeline = "pipeline"
+)
+
+// Owner identifie le job ou le pipeline qui tient ou attend un verrou. Parent désigne le
+// pipeline d'une étape : les verrous tenus par ce pipeline sont accordés à ses étapes
+type Owner struct {
+	Kind   string
+	ID     string
+	Parent string
+}
+
+// Holder décrit un détenteur ou un demandeur d'un verrou
+type Holder struct {
+	Owner Owner
+	Since time.Time
+}
+
+// Status décrit l'état d'un verrou nommé
+type Status struct {
+	Name    string
+	Limit   int
+	Holders []Holder
+	Waiters []Holder
+}
+
+// Validate vérifie les verrous déclarés par un job ou un pipeline
+func Validate(locks []models.ResourceLock) error {
+	seen := make(map[string]bool)
+	for _, l := range locks {
+		if l.Name == "" {
+			return fmt.Errorf("lock name must not be empty")
+		}
+		if l.Limit < 0 {
+			return fmt.Errorf("lock %s limit must not be negative", l.Name)
+		}
+		if seen[l.Name] {
+			return fmt.Errorf("lock %s is declared more than once", l.Name)
+		}
+		seen[l.Name] = true
+	}
+	return nil
+}
+
+type entry struct {
+	limit   int
+	holders []Holder
+	waiters []Holder
+}
+
+// Registry attribue les verrous nommés. Les verrous d'un demandeur sont acquis tous
+// ensemble ou pas du tout, ce qui évite les interblocages entre demandeurs
+type Registry struct {
+	mu      sync.Mutex
+	locks   map[string]*entry
+	changed chan struct{}
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		locks:   make(map[string]*entry),
+		changed: make(chan struct{}),
+	}
+}
+
+// Acquire attend que tous les verrous soient disponibles pour owner et retourne la fonction
+// qui les libère. L'attente s'interrompt avec une erreur lorsque ctx est annulé ou lorsqu'un
+// verrou est tenu avec une autre limite que celle demandée
+func (r *Registry) Acquire(ctx context.Context, owner Owner, locks []models.ResourceLock) (func(), error) {
+	if len(locks) == 0 {
+		return func() {}, nil
+	}
+
+	r.mu.Lock()
+	waiter := Holder{Owner: owner, Since: time.Now()}
+	for _, l := range locks {
+		e := r.entry(l.Name)
+		if len(e.holders) == 0 {
+			e.limit = limitOf(l)
+		}
+		e.waiters = append(e.waiters, waiter)
+	}
+
+	for {
+		acquired, ok, err := r.tryAcquire(owner, locks)
+		if err != nil || ok {
+			r.removeWaiter(owner, locks)
+			r.mu.Unlock()
+			if err != nil {
+				return nil, err
+			}
+			return func() { r.release(owner, acquired) }, nil
+		}
+
+		changed := r.changed
+		r.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			r.mu.Lock()
+			r.removeWaiter(owner, locks)
+			r.mu.Unlock()
+			return nil, ctx.Err()
+		case <-changed:
+		}
+		r.mu.Lock()
+	}
+}
+
+// TryAcquire prend sans attendre tous les verrous pour owner et retourne la fonction qui les
+// libère. Lorsqu'ils ne sont pas tous disponibles, owner est inscrit parmi les demandeurs
+// jusqu'à ce qu'il les obtienne ou appelle Forget
+func (r *Registry) TryAcquire(owner Owner, locks []models.ResourceLock) (func(), bool, error) {
+	if len(locks) == 0 {
+		return func() {}, true, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, l := range locks {
+		r.entry(l.Name)
+	}
+	acquired, ok, err := r.tryAcquire(owner, locks)
+	if err != nil || ok {
+		r.removeWaiter(owner, locks)
+		if err != nil {
+			return nil, false, err
+		}
+		return func() { r.release(owner, acquired) }, true, nil
+	}
+
+	waiter := Holder{Owner: owner, Since: time.Now()}
+	for _, l := range locks {
+		if e := r.locks[l.Name]; !e.waiting(owner) {
+			e.waiters = append(e.waiters, waiter)
+		}
+	}
+	return nil, false, nil
+}
+
+// Forget retire owner des demandeurs inscrits par TryAcquire
+func (r *Registry) Forget(owner Owner, locks []models.ResourceLock) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.removeWaiter(owner, locks)
+}
+
+// Changed retourne un canal fermé à la prochaine libération d'un verrou
+func (r *Registry) Changed() <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.changed
+}
+
+// tryAcquire prend les verrous s'ils sont tous disponibles et retourne les noms de ceux
+// effectivement pris. La limite d'un verrou tenu est celle de ses détenteurs : une demande
+// avec une autre limite est refusée. Doit être appelée avec r.mu verrouillé
+func (r *Registry) tryAcquire(owner Owner, locks []models.ResourceLock) ([]string, bool, error) {
+	var needed []models.ResourceLock
+	for _, l := range locks {
+		e := r.locks[l.Name]
+		if owner.Parent != "" && e.heldBy(owner.Parent) {
+			continue
+		}
+		if len(e.holders) > 0 && e.limit != limitOf(l) {
+			return nil, false, fmt.Errorf("lock %s is held with a limit of %d, not %d", l.Name, e.limit, limitOf(l))
+		}
+		if len(e.holders) >= limitOf(l) {
+			return nil, false, nil
+		}
+		needed = append(needed, l)
+	}
+
+	now := time.Now()
+	names := make([]string, 0, len(needed))
+	for _, l := range needed {
+		e := r.locks[l.Name]
+		e.limit = limitOf(l)
+		e.holders = append(e.holders, Holder{Owner: owner, Since: now})
+		names = append(names, l.Name)
+	}
+	return names, true, nil
+}
+
+func (r *Registry) release(owner Owner, names []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, name := range names {
+		e := r.locks[name]
+		for i, h := range e.holders {
+			if h.Owner == owner {
+				e.holders = append(e.holders[:i], e.holders[i+1:]...)
+				break
+			}
+		}
+		r.cleanup(name)
+	}
+	r.notify()
+}
+
+// removeWaiter retire owner des files d'attente. Doit être appelée avec r.mu verrouillé
+func (r *Registry) removeWaiter(owner Owner, locks []models.ResourceLock) {
+	for _, l := range locks {
+		e := r.locks[l.Name]
+		if e == nil {
+			continue
+		}
+		for i, w := range e.waiters {
+			if w.Owner == owner {
+				e.waiters = append(e.waiters[:i], e.waiters[i+1:]...)
+				break
+			}
+		}
+		r.cleanup(l.Name)
+	}
+}
+
+// Snapshot retourne l'état des verrous tenus ou attendus, triés par nom
+func (r *Registry) Snapshot() []Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	statuses := make([]Status, 0, len(r.locks))
+	for name, e := range r.locks {
+		statuses = append(statuses, Status{
+			Name:    name,
+			Limit:   e.limit,
+			Holders: append([]Holder(nil), e.holders...),
+			Waiters: append([]Holder(nil), e.waiters...),
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+func (r *Registry) entry(name string) *entry {
+	e, exists := r.locks[name]
+	if !exists {
+		e = &entry{limit: 1}
+		r.locks[name] = e
+	}
+	return e
+}
+
+// cleanup oublie un verrou qui n'est plus ni tenu ni attendu
+func (r *Registry) cleanup(name string) {
+	if e := r.locks[name]; e != nil && len(e.holders) == 0 && len(e.waiters) == 0 {
+		delete(r.locks, name)
+	}
+}
+
+// notify réveille les demandeurs en attente pour qu'ils retentent leur acquisition
+func (r *Registry) notify() {
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+func (e *entry) waiting(owner Owner) bool {
+	for _, w := range e.waiters {
+		if w.Owner == owner {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *entry) heldBy(id string) bool {
+	for _, h := range e.holders {
+		if h.Owner.Kind == OwnerPipeline && h.Owner.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func limitOf(l models.ResourceLock) int {
+	if l.Limit <= 0 {
+		return 1
+	}
+	return l.Limit
+}
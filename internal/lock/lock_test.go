@@ -0,0 +1,163 @@
+package lock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+)
+
+func jobOwner(id string) Owner { return Owner{Kind: OwnerJob, ID: id} }
+
+func TestTryAcquireLimits(t *testing.T) {
+	db := models.ResourceLock{Name: "db"}
+	db2 := models.ResourceLock{Name: "db", Limit: 2}
+	api := models.ResourceLock{Name: "api"}
+	pipeline := Owner{Kind: OwnerPipeline, ID: "p"}
+
+	type request struct {
+		owner   Owner
+		locks   []models.ResourceLock
+		ok      bool
+		wantErr bool
+	}
+	tests := []struct {
+		name     string
+		requests []request
+	}{
+		{"exclusive by default", []request{
+			{jobOwner("a"), []models.ResourceLock{db}, true, false},
+			{jobOwner("b"), []models.ResourceLock{db}, false, false},
+		}},
+		{"shared up to the limit", []request{
+			{jobOwner("a"), []models.ResourceLock{db2}, true, false},
+			{jobOwner("b"), []models.ResourceLock{db2}, true, false},
+			{jobOwner("c"), []models.ResourceLock{db2}, false, false},
+		}},
+		{"conflicting limit", []request{
+			{jobOwner("a"), []models.ResourceLock{db2}, true, false},
+			{jobOwner("b"), []models.ResourceLock{db}, false, true},
+		}},
+		{"all or nothing", []request{
+			{jobOwner("a"), []models.ResourceLock{api}, true, false},
+			{jobOwner("b"), []models.ResourceLock{db, api}, false, false},
+			{jobOwner("c"), []models.ResourceLock{db}, true, false},
+		}},
+		{"granted to the steps of the holding pipeline", []request{
+			{pipeline, []models.ResourceLock{db}, true, false},
+			{Owner{Kind: OwnerJob, ID: "step", Parent: "p"}, []models.ResourceLock{db}, true, false},
+			{Owner{Kind: OwnerJob, ID: "other", Parent: "q"}, []models.ResourceLock{db}, false, false},
+		}},
+		{"no lock", []request{
+			{jobOwner("a"), nil, true, false},
+			{jobOwner("b"), nil, true, false},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			for i, req := range tt.requests {
+				_, ok, err := r.TryAcquire(req.owner, req.locks)
+				if ok != req.ok || (err != nil) != req.wantErr {
+					t.Fatalf("request %d by %s = %v, %v; want %v, error %v", i, req.owner.ID, ok, err, req.ok, req.wantErr)
+				}
+			}
+		})
+	}
+}
+
+func TestReleaseAndWaiters(t *testing.T) {
+	r := NewRegistry()
+	locks := []models.ResourceLock{{Name: "db"}}
+
+	release, ok, err := r.TryAcquire(jobOwner("a"), locks)
+	if !ok || err != nil {
+		t.Fatalf("first acquisition = %v, %v", ok, err)
+	}
+	changed := r.Changed()
+	if _, ok, _ := r.TryAcquire(jobOwner("b"), locks); ok {
+		t.Fatal("held lock acquired twice")
+	}
+	if s := r.Snapshot(); len(s) != 1 || len(s[0].Holders) != 1 || len(s[0].Waiters) != 1 {
+		t.Fatalf("snapshot = %+v, want one holder and one waiter", s)
+	}
+
+	release()
+	select {
+	case <-changed:
+	default:
+		t.Fatal("release did not signal the change")
+	}
+	if _, ok, _ := r.TryAcquire(jobOwner("b"), locks); !ok {
+		t.Fatal("released lock not acquired")
+	}
+	if s := r.Snapshot(); len(s) != 1 || len(s[0].Waiters) != 0 {
+		t.Fatalf("snapshot = %+v, want the waiter removed", s)
+	}
+
+	r.Forget(jobOwner("c"), locks)
+	if _, ok, _ := r.TryAcquire(jobOwner("c"), locks); ok {
+		t.Fatal("held lock acquired twice")
+	}
+	r.Forget(jobOwner("c"), locks)
+	if s := r.Snapshot(); len(s) != 1 || len(s[0].Waiters) != 0 {
+		t.Fatalf("snapshot = %+v, want the forgotten waiter removed", s)
+	}
+}
+
+func TestAcquireWaits(t *testing.T) {
+	r := NewRegistry()
+	locks := []models.ResourceLock{{Name: "db"}}
+	release, err := r.Acquire(context.Background(), jobOwner("a"), locks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := r.Acquire(ctx, jobOwner("b"), locks); err != context.DeadlineExceeded {
+		t.Fatalf("acquisition of a held lock = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	acquired := make(chan error, 1)
+	go func() {
+		release, err := r.Acquire(context.Background(), jobOwner("c"), locks)
+		if err == nil {
+			release()
+		}
+		acquired <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	release()
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiter not woken by the release")
+	}
+	if s := r.Snapshot(); len(s) != 0 {
+		t.Fatalf("snapshot = %+v, want no lock left", s)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		locks   []models.ResourceLock
+		wantErr bool
+	}{
+		{"none", nil, false},
+		{"valid", []models.ResourceLock{{Name: "db", Limit: 2}, {Name: "api"}}, false},
+		{"empty name", []models.ResourceLock{{Name: ""}}, true},
+		{"negative limit", []models.ResourceLock{{Name: "db", Limit: -1}}, true},
+		{"declared twice", []models.ResourceLock{{Name: "db"}, {Name: "db", Limit: 2}}, true},
+	}
+	for _, tt := range tests {
+		if err := Validate(tt.locks); (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+	"errors"
 	"time"
 )
 
@@ -12,36 +14,263 @@ const (
 	JobStatusRunning   JobStatus = "running"
 	JobStatusCompleted JobStatus = "completed"
 	JobStatusFailed    JobStatus = "failed"
+	JobStatusCancelled JobStatus = "cancelled"
+	JobStatusBlocked   JobStatus = "blocked" // En attente de la fin de ses dépendances
+	JobStatusSkipped   JobStatus = "skipped" // Non exécuté suite à l'échec d'une dépendance
+	JobStatusLost      JobStatus = "lost"    // En cours lors d'un arrêt de l'orchestrateur
 
 	PipelineStatusPending   PipelineStatus = "pending"
 	PipelineStatusRunning   PipelineStatus = "running"
 	PipelineStatusCompleted PipelineStatus = "completed"
 	PipelineStatusFailed    PipelineStatus = "failed"
+	PipelineStatusLost      PipelineStatus = "lost"
+)
+
+type BackoffStrategy string
+
+const (
+	BackoffConstant    BackoffStrategy = "constant"
+	BackoffLinear      BackoffStrategy = "linear"
+	BackoffExponential BackoffStrategy = "exponential"
+)
+
+// RetryPolicy décrit quand et après quel délai un job en échec est relancé
+type RetryPolicy struct {
+	Backoff          BackoffStrategy
+	InitialDelay     time.Duration
+	MaxDelay         time.Duration
+	Jitter           float64 // Fraction aléatoire du délai, entre 0 et 1
+	RetryOnExitCodes []int
+	RetryOnOutput    []string // Expressions régulières recherchées dans stdout et stderr
+	NoRetryOnTimeout bool
+}
+
+// SuccessCriteria définit quand une tentative est considérée comme réussie
+type SuccessCriteria struct {
+	ExitCodes          []int    // Codes de sortie acceptés (0 si vide)
+	StdoutMustMatch    []string // Expressions régulières devant toutes être trouvées dans stdout
+	StdoutMustNotMatch []string // Expressions régulières ne devant pas apparaître dans stdout
+}
+
+// ResourceLimits borne les ressources consommées par le processus d'un job
+type ResourceLimits struct {
+	MemoryBytes  int64
+	CPUs         float64 // Nombre de cœurs utilisables, éventuellement fractionnaire
+	MaxProcesses int
+	MaxOpenFiles int
+}
+
+// DependencyPolicy indique le sort d'un job dont une dépendance n'a pas abouti
+type DependencyPolicy string
+
+const (
+	DependencyPolicySkip DependencyPolicy = "skip"
+	DependencyPolicyFail DependencyPolicy = "fail"
+)
+
+// RecoveryPolicy indique comment traiter au redémarrage un job ou un pipeline qui était
+// en cours lors de l'arrêt de l'orchestrateur
+type RecoveryPolicy string
+
+const (
+	RecoveryRequeue RecoveryPolicy = "requeue"
+	RecoveryLost    RecoveryPolicy = "lost"
+)
+
+// ResultFormat indique comment la sortie standard d'un job est convertie en résultat structuré
+type ResultFormat string
+
+const (
+	ResultFormatText ResultFormat = "text"
+	ResultFormatJSON ResultFormat = "json"
+	ResultFormatKV   ResultFormat = "kv"
+)
+
+// Isolation indique dans quel environnement le processus d'un job est lancé
+type Isolation string
+
+const (
+	IsolationNone    Isolation = "none"
+	IsolationSandbox Isolation = "sandbox" // Espaces de noms Linux dédiés, système de fichiers réduit
+)
+
+// ResourceLock est un verrou nommé partagé par les jobs et les pipelines. Au plus Limit
+// détenteurs peuvent le tenir en même temps (1 par défaut, soit un verrou exclusif)
+type ResourceLock struct {
+	Name  string
+	Limit int
+}
+
+// Raisons d'échec d'un job
+const (
+	FailureOOMKilled     = "oom_killed"
+	FailureLimitExceeded = "limit_exceeded"
+	FailureUpstream      = "upstream_failed"
+	FailureInterrupted   = "interrupted"
+	FailureTimedOut      = "timed_out"
 )
 
 type Job struct {
-	ID         string
-	Name       string 
-	Command    string
-	Args       []string
-	Timeout    time.Duration
-	MaxRetries int
-	Status     JobStatus
-	Result     string
-	Error      error
-	StartTime  time.Time
-	EndTime    time.Time
-	RetryCount int
-	PluginName string
+	ID               string
+	Name             string
+	Command          string
+	Args             []string
+	ResolvedArgs     []string // Arguments d'une étape après substitution du contexte du pipeline
+	Script           string   // Corps d'un job de type script, conservé pour l'audit
+	Interpreter      string
+	Env              map[string]string
+	WorkingDir       string
+	Stdin            string
+	RunAsUser        string // Utilisateur, par nom ou uid, sous lequel le processus est lancé
+	RunAsGroup       string
+	Isolation        Isolation
+	Host             string // Hôte de l'inventaire SSH sur lequel le job est exécuté (vide : hôte local)
+	Limits           *ResourceLimits
+	Locks            []ResourceLock
+	DependsOn        []string
+	DependencyPolicy DependencyPolicy
+	RecoveryPolicy   RecoveryPolicy
+	Priority         int
+	Timeout          time.Duration
+	GracePeriod      time.Duration // Délai entre SIGTERM et SIGKILL à l'expiration du timeout ou à l'annulation
+	MaxRetries       int
+	RetryPolicy      *RetryPolicy
+	Success          *SuccessCriteria
+	ResultFormat     ResultFormat
+	ArtifactGlobs    []string // Fichiers conservés après l'exécution, relatifs au workspace du job
+	StepName         string   // Nom de l'étape dans le contexte du pipeline (par défaut, le nom du job)
+	Status           JobStatus
+	Result           string // Aperçu de stdout, borné par la taille maximale configurée
+	OutputTruncated  bool
+	OutputPath       string      // Sortie complète lorsque l'aperçu a été tronqué
+	ParsedResult     interface{} // Résultat structuré extrait de stdout selon ResultFormat
+	Progress         int         // Avancement en pourcentage annoncé par le job (::progress)
+	StatusMessage    string
+	Outputs          map[string]string // Valeurs nommées émises par le job (::set-output)
+	Error            error
+	ExitCode         int
+	FailureReason    string
+	ForceKilled      bool // La dernière tentative a été tuée faute de s'arrêter dans le délai de grâce
+	QueuedAt         time.Time
+	StartTime        time.Time
+	EndTime          time.Time
+	RetryCount       int
+	Attempt          int
+	PluginName       string
+	PipelineID       string
+	ParentID         string // Job dont celui-ci est une réexécution ou une copie
 }
 
-type Pipeline struct {
-	ID          string
+// Artifact est un fichier produit par un job et conservé dans le magasin d'artefacts,
+// où son contenu est rangé sous son empreinte SHA-256
+type Artifact struct {
+	JobID     string
+	Path      string // Chemin relatif au workspace du job
+	SHA256    string
+	Size      int64
+	CreatedAt time.Time
+}
+
+// JobAttempt décrit une tentative d'exécution d'un job
+type JobAttempt struct {
+	JobID         string
+	Number        int
+	StartTime     time.Time
+	EndTime       time.Time
+	ExitCode      int
+	Signal        string
+	TimedOut      bool
+	ForceKilled   bool
+	Error         string
+	FailureReason string
+	StdoutPath    string
+	StderrPath    string
+}
+
+// Types des paramètres d'un modèle de job
+const (
+	ParamString = "string"
+	ParamInt    = "int"
+	ParamFloat  = "float"
+	ParamBool   = "bool"
+)
+
+// TemplateParam déclare un paramètre d'un modèle de job
+type TemplateParam struct {
 	Name        string
-	Jobs        []*Job
-	Status      PipelineStatus
-	StartTime   time.Time
-	EndTime     time.Time
-	Context     map[string]interface{}
-	ScheduledAt time.Time
+	Type        string
+	Required    bool
+	Default     string
+	Description string
+}
+
+// JobTemplate est une définition de job réutilisable dont les arguments contiennent
+// des emplacements {{.param}} remplacés par les paramètres fournis à l'instanciation
+type JobTemplate struct {
+	Name        string
+	Description string
+	Command     string
+	PluginName  string
+	Args        []string
+	Params      []TemplateParam
+	Timeout     time.Duration
+	MaxRetries  *int // nil : valeur par défaut de la configuration, 0 compris
+	CreatedAt   time.Time
+}
+
+// IdempotencyRecord associe une clé d'idempotence à la ressource créée par la première requête
+type IdempotencyRecord struct {
+	Key         string
+	RequestHash string
+	ResourceID  string
+	CreatedAt   time.Time
+}
+
+type Pipeline struct {
+	ID             string
+	Name           string
+	Jobs           []*Job
+	OwnedJobIDs    []string // Étapes définies en ligne, créées par le pipeline et supprimées avec lui
+	Status         PipelineStatus
+	StartTime      time.Time
+	EndTime        time.Time
+	Context        map[string]interface{}
+	ScheduledAt    time.Time
+	RetryPolicy    *RetryPolicy
+	Locks          []ResourceLock
+	RecoveryPolicy RecoveryPolicy // Vide : politique de reprise configurée par défaut
+}
+
+// MarshalJSON encode l'erreur d'un job par son message, l'interface error n'étant pas sérialisable
+func (j Job) MarshalJSON() ([]byte, error) {
+	type plainJob Job
+	var message string
+	if j.Error != nil {
+		message = j.Error.Error()
+	}
+	return json.Marshal(struct {
+		plainJob
+		Error string
+	}{plainJob(j), message})
+}
+
+// UnmarshalJSON reconstruit l'erreur d'un job à partir de son message
+func (j *Job) UnmarshalJSON(data []byte) error {
+	type plainJob Job
+	decoded := struct {
+		*plainJob
+		Error json.RawMessage
+	}{plainJob: (*plainJob)(j)}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	// Les enregistrements plus anciens contiennent un objet vide à la place du message
+	var message string
+	if json.Unmarshal(decoded.Error, &message) == nil && message != "" {
+		j.Error = errors.New(message)
+	} else {
+		j.Error = nil
+	}
+	return nil
 }
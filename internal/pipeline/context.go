@@ -0,0 +1,87 @@
+package pipeline
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+)
+
+// StepName retourne le nom sous lequel une étape est rangée dans le contexte du pipeline
+func StepName(j *models.Job) string {
+	if j.StepName != "" {
+		return j.StepName
+	}
+	if j.Name != "" {
+		return j.Name
+	}
+	return j.ID
+}
+
+// recordStep ajoute le résultat d'une étape au contexte du pipeline, sous l'identifiant du job
+// et sous steps.<nom>. Les tables sont remplacées plutôt que modifiées, le contexte pouvant
+// être lu par l'API pendant l'exécution
+func recordStep(p *models.Pipeline, j *models.Job) {
+	steps := make(map[string]interface{})
+	if previous, ok := p.Context["steps"].(map[string]interface{}); ok {
+		for name, step := range previous {
+			steps[name] = step
+		}
+	}
+
+	// Sans format de résultat, les valeurs émises avec ::set-output tiennent lieu de sorties
+	var outputs interface{} = j.ParsedResult
+	if outputs == nil && j.Outputs != nil {
+		outputs = j.Outputs
+	}
+	steps[StepName(j)] = map[string]interface{}{
+		"id":      j.ID,
+		"status":  string(j.Status),
+		"result":  j.Result,
+		"outputs": outputs,
+	}
+
+	context := make(map[string]interface{}, len(p.Context)+2)
+	for key, value := range p.Context {
+		context[key] = value
+	}
+	context[j.ID] = j.Result
+	context["steps"] = steps
+	p.Context = context
+}
+
+// LookupContext retourne la valeur désignée par un chemin pointé du contexte d'un pipeline,
+// par exemple steps.fetch.outputs.count. Un élément numérique indexe une liste
+func LookupContext(context map[string]interface{}, path string) (interface{}, error) {
+	var value interface{} = context
+	if path == "" {
+		return value, nil
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		switch current := value.(type) {
+		case map[string]interface{}:
+			next, exists := current[key]
+			if !exists {
+				return nil, fmt.Errorf("context has no value at %s", path)
+			}
+			value = next
+		case map[string]string:
+			next, exists := current[key]
+			if !exists {
+				return nil, fmt.Errorf("context has no value at %s", path)
+			}
+			value = next
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(current) {
+				return nil, fmt.Errorf("context has no value at %s", path)
+			}
+			value = current[index]
+		default:
+			return nil, fmt.Errorf("context has no value at %s", path)
+		}
+	}
+	return value, nil
+}
@@ -8,26 +8,31 @@ import (
 
 	"github.com/chrlesur/orchestrator/internal/db"
 	"github.com/chrlesur/orchestrator/internal/job"
+	"github.com/chrlesur/orchestrator/internal/lock"
 	"github.com/chrlesur/orchestrator/internal/models"
-	"github.com/chrlesur/orchestrator/internal/plugin"
+	"github.com/chrlesur/orchestrator/internal/workerpool"
 	"github.com/chrlesur/orchestrator/pkg/logger"
 )
 
 type Manager struct {
-	pipelines     map[string]*models.Pipeline
-	pipelineQueue chan *models.Pipeline
-	mu            sync.Mutex
-	wg            sync.WaitGroup
-	store         *db.Store
-	pluginManager *plugin.PluginManager
+	pipelines      map[string]*models.Pipeline
+	pipelineQueue  chan *models.Pipeline
+	mu             sync.Mutex
+	wg             sync.WaitGroup
+	store          *db.Store
+	jobManager     *job.Manager
+	workers        *workerpool.Pool
+	recoveryPolicy models.RecoveryPolicy // Politique de reprise des pipelines qui n'en définissent pas
+	recovery       RecoveryReport
 }
 
-func NewManager(workerCount int, store *db.Store, pluginManager *plugin.PluginManager) *Manager {
+func NewManager(workerCount int, store *db.Store, jobManager *job.Manager, recoveryPolicy models.RecoveryPolicy) *Manager {
 	m := &Manager{
-		pipelines:     make(map[string]*models.Pipeline),
-		pipelineQueue: make(chan *models.Pipeline, 100),
-		store:         store,
-		pluginManager: pluginManager,
+		pipelines:      make(map[string]*models.Pipeline),
+		pipelineQueue:  make(chan *models.Pipeline, 100),
+		store:          store,
+		jobManager:     jobManager,
+		recoveryPolicy: recoveryPolicy,
 	}
 
 	// Charger les pipelines existants depuis la base de données
@@ -38,10 +43,12 @@ func NewManager(workerCount int, store *db.Store, pluginManager *plugin.PluginMa
 		for _, pipeline := range pipelines {
 			m.pipelines[pipeline.ID] = pipeline
 		}
+		m.recoverPipelines(pipelines)
 	}
 
-	for i := 0; i < workerCount; i++ {
-		go m.worker()
+	m.workers = workerpool.New(m.worker)
+	if err := m.workers.Resize(workerCount); err != nil {
+		logger.Error(fmt.Sprintf("Failed to start pipeline workers: %v", err))
 	}
 
 	go m.scheduler()
@@ -53,15 +60,18 @@ func (m *Manager) AddPipeline(pipeline *models.Pipeline) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if err := job.ValidatePipelineID(pipeline.ID); err != nil {
+		return err
+	}
 	if _, exists := m.pipelines[pipeline.ID]; exists {
 		return fmt.Errorf("pipeline with ID %s already exists", pipeline.ID)
 	}
 
-	m.pipelines[pipeline.ID] = pipeline
 	err := m.store.SavePipeline(pipeline)
 	if err != nil {
 		return fmt.Errorf("failed to save pipeline to database: %v", err)
 	}
+	m.pipelines[pipeline.ID] = pipeline
 
 	return nil
 }
@@ -111,16 +121,29 @@ func (m *Manager) DeletePipeline(id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, exists := m.pipelines[id]; !exists {
+	pipeline, exists := m.pipelines[id]
+	if !exists {
 		return fmt.Errorf("pipeline with ID %s not found", id)
 	}
+	switch pipeline.Status {
+	case models.PipelineStatusCompleted, models.PipelineStatusFailed, models.PipelineStatusLost:
+	default:
+		return fmt.Errorf("pipeline %s cannot be deleted in status %s", id, pipeline.Status)
+	}
 
+	if err := m.store.DeletePipeline(id); err != nil {
+		return fmt.Errorf("failed to delete pipeline from database: %v", err)
+	}
 	delete(m.pipelines, id)
-	// Implémentez la méthode DeletePipeline dans le store si nécessaire
-	// err := m.store.DeletePipeline(id)
-	// if err != nil {
-	//     return fmt.Errorf("failed to delete pipeline from database: %v", err)
-	// }
+
+	// Les étapes créées par le pipeline et son répertoire de travail sont supprimés avec lui.
+	// Les jobs autonomes référencés par le pipeline sont conservés
+	for _, jobID := range pipeline.OwnedJobIDs {
+		if err := m.jobManager.DeleteStep(jobID); err != nil {
+			logger.Warning(fmt.Sprintf("Could not delete job %s of pipeline %s: %v", jobID, id, err))
+		}
+	}
+	m.jobManager.DeletePipelineWorkspace(id)
 
 	logger.Info(fmt.Sprintf("Pipeline %s deleted", id))
 	return nil
@@ -137,10 +160,41 @@ func (m *Manager) GetPipelines() []*models.Pipeline {
 	return pipelines
 }
 
+// ResizeWorkers modifie à chaud le nombre de workers sans interrompre les pipelines en cours
+func (m *Manager) ResizeWorkers(count int) error {
+	if err := m.workers.Resize(count); err != nil {
+		return err
+	}
+	logger.Info(fmt.Sprintf("Pipeline worker pool resized to %d", count))
+	return nil
+}
+
+// WorkerStats retourne la taille du pool de workers et le nombre de workers occupés
+func (m *Manager) WorkerStats() workerpool.Stats {
+	return m.workers.Stats()
+}
+
 func (m *Manager) worker() {
-	for pipeline := range m.pipelineQueue {
+	for {
+		if m.workers.Retire() {
+			return
+		}
+
+		var pipeline *models.Pipeline
+		select {
+		case p, ok := <-m.pipelineQueue:
+			if !ok {
+				return
+			}
+			pipeline = p
+		case <-m.workers.Wake():
+			continue
+		}
+
 		logger.Info(fmt.Sprintf("Starting pipeline %s", pipeline.ID))
+		m.workers.Begin()
 		err := m.executePipeline(pipeline)
+		m.workers.Done()
 		if err != nil {
 			logger.Error(fmt.Sprintf("Pipeline %s failed: %v", pipeline.ID, err))
 		} else {
@@ -152,21 +206,41 @@ func (m *Manager) worker() {
 }
 
 func (m *Manager) executePipeline(p *models.Pipeline) error {
+	// Les verrous du pipeline sont tenus pendant toute son exécution et accordés à ses étapes
+	if len(p.Locks) > 0 {
+		logger.Info(fmt.Sprintf("Pipeline %s waiting for locks", p.ID))
+	}
+	owner := lock.Owner{Kind: lock.OwnerPipeline, ID: p.ID}
+	release, err := m.jobManager.Locks().Acquire(context.Background(), owner, p.Locks)
+	if err != nil {
+		p.Status = models.PipelineStatusFailed
+		p.EndTime = time.Now()
+		return err
+	}
+	defer release()
+
 	p.Status = models.PipelineStatusRunning
 	p.StartTime = time.Now()
 	defer func() { p.EndTime = time.Now() }()
 
 	for _, j := range p.Jobs {
-		var err error
-		if j.PluginName != "" {
-			args := make(map[string]interface{})
-			for i, arg := range j.Args {
-				args[fmt.Sprintf("arg%d", i)] = arg
-			}
-			_, err = m.pluginManager.ExecutePlugin(j.PluginName, args)
-		} else {
-			err = job.Execute(j, context.Background())
+		// La politique de nouvelles tentatives du pipeline s'applique aux jobs qui n'en ont pas
+		if j.RetryPolicy == nil && p.RetryPolicy != nil {
+			j.RetryPolicy = p.RetryPolicy
+		}
+
+		// Les arguments peuvent faire référence aux résultats des étapes précédentes
+		if err := job.ResolveArgs(j, p.Context); err != nil {
+			j.Status = models.JobStatusFailed
+			j.Error = err
+			j.EndTime = time.Now()
+			p.Status = models.PipelineStatusFailed
+			logger.Error(fmt.Sprintf("Pipeline %s failed: could not resolve arguments of job %s: %v", p.ID, j.ID, err))
+			return err
 		}
+
+		// Le job est exécuté via le gestionnaire de jobs afin de pouvoir être annulé
+		err := m.jobManager.RunStep(j, p.ID)
 		if err != nil {
 			p.Status = models.PipelineStatusFailed
 			logger.Error(fmt.Sprintf("Pipeline %s failed: job %s encountered an error: %v", p.ID, j.ID, err))
@@ -174,7 +248,10 @@ func (m *Manager) executePipeline(p *models.Pipeline) error {
 		}
 
 		// Agréger le contexte du job dans le contexte du pipeline
-		p.Context[j.ID] = j.Result
+		recordStep(p, j)
+		if err := m.store.SavePipeline(p); err != nil {
+			logger.Error(fmt.Sprintf("Failed to save pipeline %s: %v", p.ID, err))
+		}
 	}
 
 	p.Status = models.PipelineStatusCompleted
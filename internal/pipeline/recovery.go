@@ -0,0 +1,84 @@
+package pipeline
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chrlesur/orchestrator/internal/models"
+	"github.com/chrlesur/orchestrator/pkg/logger"
+)
+
+// RecoveryReport résume la reprise des pipelines qui étaient en cours lors du dernier arrêt
+type RecoveryReport struct {
+	Time     time.Time
+	Requeued []string
+	Lost     []string
+}
+
+// recoverPipelines traite les pipelines restés en cours lors de l'arrêt précédent. Un pipeline
+// remis en file est exécuté de nouveau depuis sa première étape par le planificateur
+func (m *Manager) recoverPipelines(pipelines []*models.Pipeline) {
+	m.recovery = RecoveryReport{Time: time.Now()}
+
+	for _, p := range pipelines {
+		if p.Status != models.PipelineStatusRunning {
+			continue
+		}
+
+		policy := p.RecoveryPolicy
+		if policy == "" {
+			policy = m.recoveryPolicy
+		}
+
+		if policy == models.RecoveryRequeue {
+			// Seules les étapes créées par le pipeline sont remises à zéro ; les jobs existants
+			// auxquels il fait référence gardent l'état repris par le gestionnaire de jobs
+			owned := make(map[string]bool, len(p.OwnedJobIDs))
+			for _, id := range p.OwnedJobIDs {
+				owned[id] = true
+			}
+			for i, step := range p.Jobs {
+				if owned[step.ID] {
+					p.Jobs[i] = m.jobManager.ResetStep(step)
+				} else if tracked, err := m.jobManager.GetJob(step.ID); err == nil {
+					p.Jobs[i] = tracked
+				}
+			}
+			p.Status = models.PipelineStatusPending
+			p.Context = make(map[string]interface{})
+			p.StartTime = time.Time{}
+			p.EndTime = time.Time{}
+			m.recovery.Requeued = append(m.recovery.Requeued, p.ID)
+			logger.Warning(fmt.Sprintf("Pipeline %s was running at shutdown and is requeued", p.ID))
+		} else {
+			// Les étapes enregistrées par le gestionnaire de jobs, déjà reprises, font foi
+			for i, step := range p.Jobs {
+				if tracked, err := m.jobManager.GetJob(step.ID); err == nil {
+					p.Jobs[i] = tracked
+					step = tracked
+				}
+				if step.Status == models.JobStatusRunning {
+					step.Status = models.JobStatusLost
+					step.FailureReason = models.FailureInterrupted
+					step.EndTime = m.recovery.Time
+				}
+			}
+			p.Status = models.PipelineStatusLost
+			p.EndTime = m.recovery.Time
+			m.recovery.Lost = append(m.recovery.Lost, p.ID)
+			logger.Warning(fmt.Sprintf("Pipeline %s was running at shutdown and is marked as lost", p.ID))
+		}
+		if err := m.store.SavePipeline(p); err != nil {
+			logger.Error(fmt.Sprintf("Failed to save recovered pipeline %s: %v", p.ID, err))
+		}
+	}
+
+	if len(m.recovery.Requeued) > 0 || len(m.recovery.Lost) > 0 {
+		logger.Info(fmt.Sprintf("Pipeline recovery: %d requeued, %d lost", len(m.recovery.Requeued), len(m.recovery.Lost)))
+	}
+}
+
+// Recovery retourne le résumé de la reprise effectuée au démarrage
+func (m *Manager) Recovery() RecoveryReport {
+	return m.recovery
+}
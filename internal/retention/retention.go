@@ -0,0 +1,160 @@
+package retention
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/chrlesur/orchestrator/internal/db"
+	"github.com/chrlesur/orchestrator/internal/job"
+	"github.com/chrlesur/orchestrator/internal/pipeline"
+	"github.com/chrlesur/orchestrator/pkg/logger"
+)
+
+// Rule fixe la durée de conservation des jobs ou pipelines d'un statut donné. Un élément
+// est supprimé lorsqu'il a dépassé MaxAge, ou lorsqu'il ne fait pas partie des KeepLast
+// plus récents portant le même nom. Une valeur nulle désactive le critère correspondant
+type Rule struct {
+	MaxAge   time.Duration
+	KeepLast int
+}
+
+// Policy regroupe les règles de conservation par statut
+type Policy struct {
+	Interval          time.Duration
+	Jobs              map[string]Rule
+	Pipelines         map[string]Rule
+	IdempotencyWindow time.Duration
+}
+
+// Report résume un passage du nettoyage
+type Report struct {
+	JobsDeleted       int
+	PipelinesDeleted  int
+	IdempotencyPurged int
+}
+
+// Janitor supprime périodiquement les jobs et pipelines terminés qui ont expiré
+type Janitor struct {
+	policy          Policy
+	store           *db.Store
+	jobManager      *job.Manager
+	pipelineManager *pipeline.Manager
+	stop            chan struct{}
+	done            chan struct{}
+}
+
+func NewJanitor(policy Policy, store *db.Store, jobManager *job.Manager, pipelineManager *pipeline.Manager) *Janitor {
+	return &Janitor{
+		policy:          policy,
+		store:           store,
+		jobManager:      jobManager,
+		pipelineManager: pipelineManager,
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+}
+
+// Start lance un premier nettoyage puis le répète à chaque intervalle
+func (j *Janitor) Start() {
+	go func() {
+		defer close(j.done)
+		ticker := time.NewTicker(j.policy.Interval)
+		defer ticker.Stop()
+
+		for {
+			j.Run()
+			select {
+			case <-j.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (j *Janitor) Stop() {
+	close(j.stop)
+	<-j.done
+}
+
+// Run effectue un passage de nettoyage
+func (j *Janitor) Run() Report {
+	now := time.Now()
+	var report Report
+
+	// Les pipelines passent en premier : leurs étapes sont supprimées avec eux
+	var pipelines []entry
+	for _, p := range j.pipelineManager.GetPipelines() {
+		pipelines = append(pipelines, entry{id: p.ID, name: p.Name, status: string(p.Status), end: p.EndTime})
+	}
+	for _, id := range expired(pipelines, j.policy.Pipelines, now) {
+		if err := j.pipelineManager.DeletePipeline(id); err != nil {
+			logger.Warning(fmt.Sprintf("Retention could not delete pipeline %s: %v", id, err))
+			continue
+		}
+		report.PipelinesDeleted++
+	}
+
+	// Les étapes de pipeline suivent la conservation de leur pipeline
+	var jobs []entry
+	for _, jb := range j.jobManager.GetJobs() {
+		if jb.PipelineID == "" {
+			jobs = append(jobs, entry{id: jb.ID, name: jb.Name, status: string(jb.Status), end: jb.EndTime})
+		}
+	}
+	for _, id := range expired(jobs, j.policy.Jobs, now) {
+		if err := j.jobManager.DeleteJob(id); err != nil {
+			logger.Debug(fmt.Sprintf("Retention kept job %s: %v", id, err))
+			continue
+		}
+		report.JobsDeleted++
+	}
+
+	if j.policy.IdempotencyWindow > 0 {
+		purged, err := j.store.PurgeIdempotencyRecords(now.Add(-j.policy.IdempotencyWindow))
+		if err != nil {
+			logger.Error(err.Error())
+		}
+		report.IdempotencyPurged = purged
+	}
+
+	if report.JobsDeleted > 0 || report.PipelinesDeleted > 0 {
+		logger.Info(fmt.Sprintf("Retention deleted %d jobs and %d pipelines", report.JobsDeleted, report.PipelinesDeleted))
+	}
+	return report
+}
+
+// entry est la vue commune d'un job ou d'un pipeline terminé
+type entry struct {
+	id     string
+	name   string
+	status string
+	end    time.Time
+}
+
+// expired retourne les identifiants des éléments terminés que leur règle ne conserve plus
+func expired(entries []entry, rules map[string]Rule, now time.Time) []string {
+	groups := make(map[string][]entry)
+	for _, e := range entries {
+		if _, ruled := rules[e.status]; !ruled || e.end.IsZero() {
+			continue
+		}
+		key := e.status + "/" + e.name
+		groups[key] = append(groups[key], e)
+	}
+
+	var ids []string
+	for _, group := range groups {
+		rule := rules[group[0].status]
+		sort.Slice(group, func(i, k int) bool { return group[i].end.After(group[k].end) })
+		for i, e := range group {
+			tooOld := rule.MaxAge > 0 && now.Sub(e.end) > rule.MaxAge
+			beyondLast := rule.KeepLast > 0 && i >= rule.KeepLast
+			if tooOld || beyondLast {
+				ids = append(ids, e.id)
+			}
+		}
+	}
+	return ids
+}
@@ -0,0 +1,37 @@
+// Package sandbox lance un processus dans des espaces de noms Linux dédiés (utilisateur, PID,
+// montage, UTS et réseau). Le processus ne voit que les chemins de l'hôte explicitement
+// partagés, un /tmp privé et aucune interface réseau en dehors de la boucle locale.
+//
+// L'orchestrateur se relance lui-même dans les nouveaux espaces de noms : Init doit donc être
+// appelée au tout début de main pour préparer le système de fichiers puis lancer la commande,
+// dont le processus relancé reste le parent.
+package sandbox
+
+import (
+	"errors"
+)
+
+// ErrUnavailable signale que le noyau ne permet pas de créer les espaces de noms nécessaires
+var ErrUnavailable = errors.New("sandbox isolation is unavailable")
+
+const (
+	// initName est le nom de processus sous lequel l'orchestrateur se relance dans le bac à sable
+	initName = "orchestrator-sandbox-init"
+	// specEnv transmet la description du bac à sable au processus relancé
+	specEnv = "ORCH_SANDBOX_SPEC"
+	// initFailure est le code de sortie du bac à sable lorsque sa préparation échoue
+	initFailure = 126
+)
+
+// Spec décrit le système de fichiers et l'identité visibles depuis le bac à sable
+type Spec struct {
+	ReadOnly   []string // Chemins de l'hôte partagés en lecture seule, au même emplacement
+	Writable   []string // Chemins de l'hôte partagés en lecture et écriture
+	WorkingDir string   // Répertoire courant du processus, / par défaut
+	Hostname   string
+	UID        int // Identité sous laquelle le processus s'exécute, la même sur l'hôte et dans le bac à sable
+	GID        int
+}
+
+// DefaultPaths sont les chemins partagés lorsque la configuration n'en précise pas
+var DefaultPaths = []string{"/bin", "/sbin", "/usr", "/lib", "/lib64"}
@@ -0,0 +1,485 @@
+package sandbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// Point de montage temporaire à partir duquel la nouvelle racine est construite. Le montage
+	// n'est visible que dans l'espace de noms du bac à sable
+	baseDir = "/tmp"
+	oldRoot = "/oldroot"
+	newRoot = "/newroot"
+
+	// Options de montage verrouillées par le noyau, à conserver lors d'un remontage en lecture
+	// seule. Statfs les rapporte avec les mêmes valeurs, sauf relatime
+	lockedMountFlags = syscall.MS_NOSUID | syscall.MS_NODEV | syscall.MS_NOEXEC |
+		syscall.MS_NOATIME | syscall.MS_NODIRATIME
+	stRelatime = 0x1000
+
+	// nobodyID remplace root comme identité dans le bac à sable
+	nobodyID = 65534
+
+	capSetpcap  = 8
+	capNetAdmin = 12
+	capSysAdmin = 21
+
+	prCapbsetDrop     = 24
+	prSetNoNewPrivs   = 38
+	prCapAmbient      = 47
+	prCapAmbientClear = 4
+	linuxCapVersion3  = 0x20080522
+	defaultLastCap    = 40
+)
+
+// Périphériques de l'hôte exposés dans le /dev du bac à sable
+var devices = []string{"null", "zero", "full", "random", "urandom"}
+
+// Capacités nécessaires à la préparation du bac à sable : montages, nom d'hôte, interface
+// réseau et abandon des capacités
+var setupCapabilities = []uintptr{capSysAdmin, capNetAdmin, capSetpcap}
+
+// Signaux relayés par le processus init du bac à sable au groupe de processus de la commande
+var forwardedSignals = []os.Signal{syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP, syscall.SIGQUIT,
+	syscall.SIGUSR1, syscall.SIGUSR2}
+
+// Available vérifie que le noyau autorise la création d'espaces de noms utilisateur
+func Available() error {
+	if _, err := os.Stat("/proc/self/ns/user"); err != nil {
+		return fmt.Errorf("%w: the kernel does not support user namespaces", ErrUnavailable)
+	}
+	if readSysctl("user/max_user_namespaces") == "0" {
+		return fmt.Errorf("%w: user namespaces are disabled (user.max_user_namespaces = 0)", ErrUnavailable)
+	}
+	if os.Geteuid() == 0 {
+		return nil
+	}
+	if readSysctl("kernel/unprivileged_userns_clone") == "0" {
+		return fmt.Errorf("%w: unprivileged user namespaces are disabled (kernel.unprivileged_userns_clone = 0)", ErrUnavailable)
+	}
+	if readSysctl("kernel/apparmor_restrict_unprivileged_userns") == "1" {
+		return fmt.Errorf("%w: unprivileged user namespaces are restricted by AppArmor (kernel.apparmor_restrict_unprivileged_userns = 1)", ErrUnavailable)
+	}
+	return nil
+}
+
+func readSysctl(name string) string {
+	data, err := ioutil.ReadFile(filepath.Join("/proc/sys", name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// Wrap modifie une commande préparée pour qu'elle s'exécute dans un bac à sable décrit par
+// spec. L'identité du processus est celle de spec : un éventuel Credential est remplacé
+func Wrap(cmd *exec.Cmd, spec Spec) error {
+	if err := Available(); err != nil {
+		return err
+	}
+
+	// Les chemins relatifs sont interprétés depuis le répertoire de l'orchestrateur
+	var err error
+	if spec.ReadOnly, err = absPaths(spec.ReadOnly); err != nil {
+		return err
+	}
+	if spec.Writable, err = absPaths(spec.Writable); err != nil {
+		return err
+	}
+	if spec.WorkingDir == "" && cmd.Dir != "" {
+		spec.WorkingDir = cmd.Dir
+	}
+	if spec.WorkingDir != "" {
+		if spec.WorkingDir, err = filepath.Abs(spec.WorkingDir); err != nil {
+			return err
+		}
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd.Env = append(env, specEnv+"="+string(data))
+	cmd.Args = append([]string{initName, cmd.Path}, cmd.Args...)
+	cmd.Path = "/proc/self/exe"
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	attr := cmd.SysProcAttr
+	attr.Cloneflags |= syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID |
+		syscall.CLONE_NEWUTS | syscall.CLONE_NEWNET | syscall.CLONE_NEWIPC
+	// Seule l'identité demandée est définie dans le bac à sable, avec le même numéro que sur
+	// l'hôte, et root n'y existe pas. Le processus ne garde que les capacités nécessaires à la
+	// préparation du bac à sable, abandonnées avant de lancer la commande. Ses groupes
+	// secondaires sont abandonnés lorsque le noyau l'autorise, c'est-à-dire pour root
+	privileged := os.Geteuid() == 0
+	uid, gid := innerID(spec.UID), innerID(spec.GID)
+	attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: uid, HostID: spec.UID, Size: 1}}
+	attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: gid, HostID: spec.GID, Size: 1}}
+	attr.GidMappingsEnableSetgroups = privileged
+	attr.Credential = &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid), NoSetGroups: !privileged}
+	attr.AmbientCaps = setupCapabilities
+	return nil
+}
+
+// innerID retourne l'identité du processus dans le bac à sable, jamais root
+func innerID(hostID int) int {
+	if hostID == 0 {
+		return nobodyID
+	}
+	return hostID
+}
+
+func absPaths(paths []string) ([]string, error) {
+	abs := make([]string, 0, len(paths))
+	for _, p := range paths {
+		a, err := filepath.Abs(p)
+		if err != nil {
+			return nil, err
+		}
+		abs = append(abs, a)
+	}
+	return abs, nil
+}
+
+// StartError précise l'erreur de démarrage d'une commande enveloppée par Wrap lorsque le
+// noyau a refusé de créer les espaces de noms
+func StartError(err error) error {
+	for _, errno := range []syscall.Errno{syscall.EPERM, syscall.EINVAL, syscall.ENOSPC, syscall.EUSERS} {
+		if errors.Is(err, errno) {
+			return fmt.Errorf("%w: could not create namespaces: %v", ErrUnavailable, err)
+		}
+	}
+	return err
+}
+
+// Init prépare le bac à sable puis exécute la commande lorsque le processus courant a été
+// lancé par Wrap. Dans tous les autres cas, la fonction ne fait rien
+func Init() {
+	if len(os.Args) < 3 || os.Args[0] != initName {
+		return
+	}
+	if err := start(os.Args[1], os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+		os.Exit(initFailure)
+	}
+}
+
+func start(path string, argv []string) error {
+	var spec Spec
+	if err := json.Unmarshal([]byte(os.Getenv(specEnv)), &spec); err != nil {
+		return fmt.Errorf("invalid sandbox description: %v", err)
+	}
+	os.Unsetenv(specEnv)
+
+	if err := setupRoot(&spec); err != nil {
+		return err
+	}
+	if spec.Hostname != "" {
+		if err := syscall.Sethostname([]byte(spec.Hostname)); err != nil {
+			return fmt.Errorf("could not set hostname: %v", err)
+		}
+	}
+	if err := loopbackUp(); err != nil {
+		return fmt.Errorf("could not bring up loopback interface: %v", err)
+	}
+
+	workingDir := spec.WorkingDir
+	if workingDir == "" {
+		workingDir = "/"
+	}
+	if err := os.Chdir(workingDir); err != nil {
+		return fmt.Errorf("working directory %s is not available in the sandbox: %v", workingDir, err)
+	}
+
+	// Le processus reste le premier de l'espace de noms PID : sa fin arrête tout le bac à sable
+	return runInit(path, argv)
+}
+
+// runInit lance la commande dans son propre groupe de processus, lui relaie les signaux d'arrêt
+// et récupère les processus orphelins du bac à sable. Il se termine avec la commande, en
+// rapportant une fin par signal avec le code 128 + signal, comme un shell
+func runInit(path string, argv []string) error {
+	signals := make(chan os.Signal, 8)
+	signal.Notify(signals, forwardedSignals...)
+
+	// Les capacités sont propres à chaque thread : la commande est lancée depuis celui qui les
+	// a abandonnées
+	runtime.LockOSThread()
+	if err := dropPrivileges(); err != nil {
+		return fmt.Errorf("could not drop privileges: %v", err)
+	}
+	pid, err := syscall.ForkExec(path, argv, &syscall.ProcAttr{
+		Env:   os.Environ(),
+		Files: []uintptr{0, 1, 2},
+		Sys:   &syscall.SysProcAttr{Setpgid: true},
+	})
+	runtime.UnlockOSThread()
+	if err != nil {
+		return fmt.Errorf("could not execute %s: %v", path, err)
+	}
+	go func() {
+		for sig := range signals {
+			syscall.Kill(-pid, sig.(syscall.Signal))
+		}
+	}()
+
+	for {
+		var status syscall.WaitStatus
+		reaped, err := syscall.Wait4(-1, &status, 0, nil)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("could not wait for %s: %v", path, err)
+		}
+		if reaped != pid {
+			continue
+		}
+		if status.Signaled() {
+			os.Exit(128 + int(status.Signal()))
+		}
+		os.Exit(status.ExitStatus())
+	}
+}
+
+// dropPrivileges retire au thread courant toutes ses capacités, y compris celles qu'un exec
+// pourrait lui rendre, et interdit à ses descendants d'en acquérir de nouvelles
+func dropPrivileges() error {
+	if err := prctl(prSetNoNewPrivs, 1, 0); err != nil {
+		return fmt.Errorf("could not set no_new_privs: %v", err)
+	}
+	for c := 0; c <= lastCapability(); c++ {
+		if err := prctl(prCapbsetDrop, uintptr(c), 0); err != nil && err != syscall.EINVAL {
+			return fmt.Errorf("could not drop capability %d from the bounding set: %v", c, err)
+		}
+	}
+	if err := prctl(prCapAmbient, prCapAmbientClear, 0); err != nil {
+		return fmt.Errorf("could not clear ambient capabilities: %v", err)
+	}
+
+	// struct __user_cap_header_struct suivie de deux struct __user_cap_data_struct vides
+	header := struct {
+		version uint32
+		pid     int32
+	}{version: linuxCapVersion3}
+	var data [2]struct{ effective, permitted, inheritable uint32 }
+	_, _, errno := syscall.RawSyscall(syscall.SYS_CAPSET, uintptr(unsafe.Pointer(&header)),
+		uintptr(unsafe.Pointer(&data[0])), 0)
+	if errno != 0 {
+		return fmt.Errorf("could not clear capabilities: %v", errno)
+	}
+	return nil
+}
+
+// lastCapability retourne la dernière capacité connue du noyau
+func lastCapability() int {
+	if last, err := strconv.Atoi(readSysctl("kernel/cap_last_cap")); err == nil {
+		return last
+	}
+	return defaultLastCap
+}
+
+func prctl(option, arg2, arg3 uintptr) error {
+	_, _, errno := syscall.RawSyscall6(syscall.SYS_PRCTL, option, arg2, arg3, 0, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// setupRoot construit la nouvelle racine sur un tmpfs à partir de l'ancienne, temporairement
+// accessible sous /oldroot, puis s'y place et détache le système de fichiers de l'hôte
+func setupRoot(spec *Spec) error {
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("could not make mounts private: %v", err)
+	}
+	if err := mountTmpfs(baseDir, "0755"); err != nil {
+		return err
+	}
+	for _, dir := range []string{oldRoot, newRoot} {
+		if err := os.Mkdir(baseDir+dir, 0755); err != nil {
+			return err
+		}
+	}
+	if err := syscall.PivotRoot(baseDir, baseDir+oldRoot); err != nil {
+		return fmt.Errorf("could not change root: %v", err)
+	}
+	if err := os.Chdir("/"); err != nil {
+		return err
+	}
+
+	// /tmp et /dev sont montés en premier pour que les chemins partagés puissent s'y trouver
+	if err := mountTmpfs(newRoot, "0755"); err != nil {
+		return err
+	}
+	if err := mountTmpfs(newRoot+"/tmp", "1777"); err != nil {
+		return err
+	}
+	if err := setupDev(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(newRoot+"/proc", 0755); err != nil {
+		return err
+	}
+	if err := syscall.Mount("proc", newRoot+"/proc", "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("could not mount /proc: %v", err)
+	}
+
+	for _, p := range spec.ReadOnly {
+		if err := share(p, true); err != nil {
+			return err
+		}
+	}
+	for _, p := range spec.Writable {
+		if err := share(p, false); err != nil {
+			return err
+		}
+	}
+
+	if err := syscall.Unmount(oldRoot, syscall.MNT_DETACH); err != nil {
+		return fmt.Errorf("could not detach host filesystem: %v", err)
+	}
+	if err := os.Chdir(newRoot); err != nil {
+		return err
+	}
+	// L'ancienne racine est empilée sur la nouvelle puis détachée
+	if err := syscall.PivotRoot(".", "."); err != nil {
+		return fmt.Errorf("could not change root: %v", err)
+	}
+	if err := syscall.Unmount(".", syscall.MNT_DETACH); err != nil {
+		return fmt.Errorf("could not detach temporary root: %v", err)
+	}
+	if err := os.Chdir("/"); err != nil {
+		return err
+	}
+	return syscall.Mount("", "/", "", syscall.MS_REMOUNT|syscall.MS_BIND|syscall.MS_RDONLY|syscall.MS_NOSUID|syscall.MS_NODEV, "")
+}
+
+func mountTmpfs(dir, mode string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := syscall.Mount("tmpfs", dir, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode="+mode); err != nil {
+		return fmt.Errorf("could not mount tmpfs on %s: %v", dir, err)
+	}
+	return nil
+}
+
+// setupDev expose quelques périphériques de l'hôte dans un /dev privé
+func setupDev() error {
+	if err := mountTmpfs(newRoot+"/dev", "0755"); err != nil {
+		return err
+	}
+	for _, name := range devices {
+		target := newRoot + "/dev/" + name
+		if err := ioutil.WriteFile(target, nil, 0666); err != nil {
+			return err
+		}
+		if err := syscall.Mount(oldRoot+"/dev/"+name, target, "", syscall.MS_BIND, ""); err != nil {
+			return fmt.Errorf("could not expose /dev/%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// share rend un chemin de l'hôte visible au même emplacement dans le bac à sable. Les chemins
+// absents sont ignorés et les liens symboliques sont recréés tels quels
+func share(path string, readOnly bool) error {
+	source := oldRoot + path
+	target := newRoot + path
+
+	info, err := os.Lstat(source)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		link, err := os.Readlink(source)
+		if err != nil {
+			return err
+		}
+		if err := os.Symlink(link, target); err != nil && !os.IsExist(err) {
+			return err
+		}
+		return nil
+	case info.IsDir():
+		err = os.MkdirAll(target, 0755)
+	default:
+		err = ioutil.WriteFile(target, nil, 0644)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := syscall.Mount(source, target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("could not share %s: %v", path, err)
+	}
+	if !readOnly {
+		return nil
+	}
+
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(target, &fs); err != nil {
+		return err
+	}
+	flags := uintptr(fs.Flags) & lockedMountFlags
+	if fs.Flags&stRelatime != 0 {
+		flags |= syscall.MS_RELATIME
+	}
+	if err := syscall.Mount("", target, "", syscall.MS_REMOUNT|syscall.MS_BIND|syscall.MS_RDONLY|flags, ""); err != nil {
+		return fmt.Errorf("could not make %s read-only: %v", path, err)
+	}
+	return nil
+}
+
+// loopbackUp active l'interface lo, seule interface du nouvel espace de noms réseau
+func loopbackUp() error {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	// struct ifreq : nom de l'interface suivi des drapeaux
+	var ifr [40]byte
+	copy(ifr[:], "lo")
+	if err := ioctl(fd, syscall.SIOCGIFFLAGS, &ifr); err != nil {
+		return err
+	}
+	flags := (*uint16)(unsafe.Pointer(&ifr[syscall.IFNAMSIZ]))
+	*flags |= syscall.IFF_UP | syscall.IFF_RUNNING
+	return ioctl(fd, syscall.SIOCSIFFLAGS, &ifr)
+}
+
+func ioctl(fd int, request uintptr, ifr *[40]byte) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), request, uintptr(unsafe.Pointer(ifr)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
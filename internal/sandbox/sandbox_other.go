@@ -0,0 +1,27 @@
+//go:build !linux
+// +build !linux
+
+package sandbox
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// Available n'est satisfaite que sous Linux
+func Available() error {
+	return fmt.Errorf("%w: only supported on Linux", ErrUnavailable)
+}
+
+// Wrap n'est disponible que sous Linux
+func Wrap(cmd *exec.Cmd, spec Spec) error {
+	return Available()
+}
+
+// StartError retourne l'erreur de démarrage telle quelle
+func StartError(err error) error {
+	return err
+}
+
+// Init ne fait rien hors de Linux
+func Init() {}
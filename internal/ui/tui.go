@@ -3,6 +3,8 @@ package ui
 import (
 	"fmt"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,12 +13,17 @@ import (
 	"github.com/chrlesur/orchestrator/internal/pipeline"
 	"github.com/chrlesur/orchestrator/internal/plugin"
 	"github.com/chrlesur/orchestrator/pkg/logger"
+	"github.com/chrlesur/orchestrator/pkg/utils"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
 
+// Nom de la page de l'éditeur de script affichée au-dessus de l'interface principale
+const scriptEditorPage = "scriptEditor"
+
 type TUI struct {
 	app              *tview.Application
+	pages            *tview.Pages
 	jobManager       *job.Manager
 	pipelineManager  *pipeline.Manager
 	pluginManager    *plugin.PluginManager
@@ -33,6 +40,7 @@ type TUI struct {
 func NewTUI(jobManager *job.Manager, pipelineManager *pipeline.Manager, pluginManager *plugin.PluginManager) *TUI {
 	t := &TUI{
 		app:             tview.NewApplication(),
+		pages:           tview.NewPages(),
 		jobManager:      jobManager,
 		pipelineManager: pipelineManager,
 		pluginManager:   pluginManager,
@@ -106,10 +114,15 @@ func (t *TUI) setupUI() {
 		AddItem(t.inputField, 1, 0, true)
 
 	// Configuration de l'application
-	t.app.SetRoot(root, true).SetFocus(t.inputField)
+	t.pages.AddPage("main", root, true, true)
+	t.app.SetRoot(t.pages, true).SetFocus(t.inputField)
 
 	// Ajout d'un gestionnaire d'événements global pour les touches
 	t.app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		// L'éditeur de script reçoit toutes les touches, y compris Tab
+		if name, _ := t.pages.GetFrontPage(); name == scriptEditorPage && event.Key() != tcell.KeyCtrlC {
+			return event
+		}
 		switch event.Key() {
 		case tcell.KeyTab:
 			// Changement de focus entre les éléments principaux
@@ -137,10 +150,24 @@ func (t *TUI) setupUI() {
 func (t *TUI) updateJobList() {
 	t.jobList.Clear()
 	for _, job := range t.jobManager.GetJobs() {
-		t.jobList.AddItem(fmt.Sprintf("%s - %s (%s)", job.Name, job.ID, job.Status), "", 0, nil)
+		item := fmt.Sprintf("%s - %s (%s)", job.Name, job.ID, job.Status)
+		if job.Status == models.JobStatusRunning && (job.Progress > 0 || job.StatusMessage != "") {
+			progress := progressBar(job.Progress, 10)
+			if job.StatusMessage != "" {
+				progress += " " + job.StatusMessage
+			}
+			item += " " + tview.Escape(progress)
+		}
+		t.jobList.AddItem(item, "", 0, nil)
 	}
 }
 
+// progressBar dessine une barre de progression de width caractères suivie du pourcentage
+func progressBar(percent, width int) string {
+	filled := percent * width / 100
+	return fmt.Sprintf("[%s%s] %d%%", strings.Repeat("#", filled), strings.Repeat("-", width-filled), percent)
+}
+
 func (t *TUI) updatePipelineList() {
 	t.pipelineList.Clear()
 	for _, pipeline := range t.pipelineManager.GetPipelines() {
@@ -160,8 +187,88 @@ func (t *TUI) showJobDetails(index int, mainText string, secondaryText string, s
 		return
 	}
 
-	details := fmt.Sprintf("Job Name: %s\nJob ID: %s\nStatus: %s\nCommand: %s\nArgs: %v\nStart Time: %s\nEnd Time: %s\nResult: %s\nError: %v",
-		job.Name, job.ID, job.Status, job.Command, job.Args, job.StartTime, job.EndTime, job.Result, job.Error)
+	details := fmt.Sprintf("Job Name: %s\nJob ID: %s\nStatus: %s\nCommand: %s\nArgs: %v\nStart Time: %s\nEnd Time: %s\nExit Code: %d\nResult: %s\nError: %v",
+		job.Name, job.ID, job.Status, job.Command, job.Args, job.StartTime, job.EndTime, job.ExitCode, job.Result, job.Error)
+
+	if job.Script != "" {
+		details += fmt.Sprintf("\nInterpreter: %s\nScript:\n%s", job.Interpreter, job.Script)
+	}
+	if job.FailureReason != "" {
+		details += fmt.Sprintf("\nFailure Reason: %s", job.FailureReason)
+	}
+	if job.ForceKilled {
+		details += fmt.Sprintf("\nForce Killed: yes (grace period %s)", job.GracePeriod)
+	}
+	if job.Progress > 0 || job.StatusMessage != "" {
+		details += "\nProgress: " + tview.Escape(progressBar(job.Progress, 20)+" "+job.StatusMessage)
+	}
+	if len(job.Outputs) > 0 {
+		keys := make([]string, 0, len(job.Outputs))
+		for key := range job.Outputs {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		details += "\nOutputs:"
+		for _, key := range keys {
+			details += fmt.Sprintf("\n  %s=%s", key, job.Outputs[key])
+		}
+	}
+	if job.ParentID != "" {
+		details += fmt.Sprintf("\nParent Job: %s", job.ParentID)
+	}
+	if job.Host != "" {
+		details += fmt.Sprintf("\nHost: %s", job.Host)
+	}
+	if job.Isolation != "" {
+		details += fmt.Sprintf("\nIsolation: %s", job.Isolation)
+	}
+	if job.RunAsUser != "" || job.RunAsGroup != "" {
+		details += fmt.Sprintf("\nRun As: %s:%s", job.RunAsUser, job.RunAsGroup)
+	}
+	if len(job.DependsOn) > 0 {
+		policy := job.DependencyPolicy
+		if policy == "" {
+			policy = models.DependencyPolicySkip
+		}
+		details += fmt.Sprintf("\nDepends On: %s (on failure: %s)", strings.Join(job.DependsOn, ", "), policy)
+	}
+	if len(job.Locks) > 0 {
+		names := make([]string, 0, len(job.Locks))
+		for _, l := range job.Locks {
+			names = append(names, fmt.Sprintf("%s (limit %d)", l.Name, lockLimit(l)))
+		}
+		details += fmt.Sprintf("\nLocks: %s", strings.Join(names, ", "))
+	}
+
+	artifacts, err := t.jobManager.GetArtifacts(job.ID)
+	if err == nil && len(artifacts) > 0 {
+		details += "\nArtifacts:"
+		for _, a := range artifacts {
+			details += fmt.Sprintf("\n  %s (%d bytes, sha256 %s)", a.Path, a.Size, a.SHA256[:12])
+		}
+	}
+
+	attempts, err := t.jobManager.GetAttempts(job.ID)
+	if err == nil && len(attempts) > 0 {
+		details += "\nAttempts:"
+		for _, a := range attempts {
+			if a.EndTime.IsZero() {
+				details += fmt.Sprintf("\n  #%d %s -> running", a.Number, a.StartTime.Format("15:04:05"))
+				continue
+			}
+			details += fmt.Sprintf("\n  #%d %s -> %s exit=%d", a.Number,
+				a.StartTime.Format("15:04:05"), a.EndTime.Format("15:04:05"), a.ExitCode)
+			if a.Signal != "" {
+				details += fmt.Sprintf(" signal=%s", a.Signal)
+			}
+			if a.ForceKilled {
+				details += " force-killed"
+			}
+			if a.Error != "" {
+				details += fmt.Sprintf(" error=%s", a.Error)
+			}
+		}
+	}
 	t.detailView.SetText(details)
 }
 
@@ -186,7 +293,7 @@ func (t *TUI) handleCommand(key tcell.Key) {
 	cmd := t.inputField.GetText()
 	t.inputField.SetText("")
 
-	parts := strings.Fields(cmd)
+	parts := splitCommandLine(cmd)
 	if len(parts) == 0 {
 		return
 	}
@@ -196,21 +303,93 @@ func (t *TUI) handleCommand(key tcell.Key) {
 		t.showHelp()
 	case "addjob":
 		t.handleAddJob(parts[1:])
+	case "addscript":
+		t.handleAddScript(parts[1:])
+	case "canceljob":
+		t.handleCancelJob(parts[1:])
+	case "rerun":
+		t.handleRerunJob(parts[1:])
+	case "clone":
+		t.handleCloneJob(parts[1:])
 	case "addpipeline":
 		t.handleAddPipeline(parts[1:])
 	case "executeplugin":
 		t.handleExecutePlugin(parts[1:])
 	case "setloglevel":
 		t.handleSetLogLevel(parts[1:])
+	case "setworkers":
+		t.handleSetWorkers(parts[1:])
+	case "locks":
+		t.showLocks()
+	case "templates":
+		t.showTemplates()
+	case "runtemplate":
+		t.handleRunTemplate(parts[1:])
 	default:
 		logger.Info(fmt.Sprintf("Unknown command: %s. Type 'help' for available commands.", parts[0]))
 		t.detailView.SetText(fmt.Sprintf("Unknown command: %s. Type 'help' for available commands.", parts[0]))
 	}
 }
 
+const addJobUsage = "Usage: addjob [-e KEY=VALUE]... [-d <working_dir>] [-i <stdin>] [-p <priority>] [-l <lock>[:<limit>]]... [-a <job_id>]... [-u <user>[:<group>]] [-r <host>] <name> <command> <arg1> <arg2> ..."
+
 func (t *TUI) handleAddJob(args []string) {
+	env := make(map[string]string)
+	var workingDir, stdin, runAsUser, runAsGroup, host string
+	var locks []models.ResourceLock
+	var dependsOn []string
+	priority := job.PriorityNormal
+
+	// Les options précèdent le nom du job
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		if len(args) < 2 {
+			t.detailView.SetText(addJobUsage)
+			return
+		}
+		switch args[0] {
+		case "-e":
+			kv := strings.SplitN(args[1], "=", 2)
+			if len(kv) != 2 {
+				t.detailView.SetText(addJobUsage)
+				return
+			}
+			env[kv[0]] = kv[1]
+		case "-d":
+			workingDir = args[1]
+		case "-i":
+			stdin = args[1]
+		case "-p":
+			p, err := job.ParsePriority(args[1])
+			if err != nil {
+				t.detailView.SetText(fmt.Sprintf("Error adding job: %v", err))
+				return
+			}
+			priority = p
+		case "-l":
+			l, err := parseLockOption(args[1])
+			if err != nil {
+				t.detailView.SetText(fmt.Sprintf("Error adding job: %v", err))
+				return
+			}
+			locks = append(locks, l)
+		case "-a":
+			dependsOn = append(dependsOn, args[1])
+		case "-u":
+			runAsUser = args[1]
+			if i := strings.Index(runAsUser, ":"); i >= 0 {
+				runAsUser, runAsGroup = runAsUser[:i], runAsUser[i+1:]
+			}
+		case "-r":
+			host = args[1]
+		default:
+			t.detailView.SetText(addJobUsage)
+			return
+		}
+		args = args[2:]
+	}
+
 	if len(args) < 3 {
-		t.detailView.SetText("Usage: addjob <name> <command> <arg1> <arg2> ...")
+		t.detailView.SetText(addJobUsage)
 		return
 	}
 
@@ -218,17 +397,337 @@ func (t *TUI) handleAddJob(args []string) {
 	command := args[1]
 	jobArgs := args[2:]
 
-	job, err := t.jobManager.CreateJob(name, command, jobArgs, "")
+	newJob := t.jobManager.PrepareJob(name, command, jobArgs, "")
+	newJob.Env = env
+	newJob.WorkingDir = workingDir
+	newJob.Stdin = stdin
+	newJob.Priority = priority
+	newJob.Locks = locks
+	newJob.DependsOn = dependsOn
+	newJob.RunAsUser = runAsUser
+	newJob.RunAsGroup = runAsGroup
+	newJob.Host = host
+
+	err := t.jobManager.SubmitJob(newJob)
 	if err != nil {
 		logger.Error(fmt.Sprintf("Error adding job: %v", err))
 		t.detailView.SetText(fmt.Sprintf("Error adding job: %v", err))
 	} else {
-		logger.Info(fmt.Sprintf("Job added: %s (ID: %s)", name, job.ID))
-		t.detailView.SetText(fmt.Sprintf("Job added successfully: %s (ID: %s)", name, job.ID))
+		logger.Info(fmt.Sprintf("Job added: %s (ID: %s)", name, newJob.ID))
+		t.detailView.SetText(fmt.Sprintf("Job added successfully: %s (ID: %s)", name, newJob.ID))
 		t.updateJobList()
 	}
 }
 
+func (t *TUI) handleAddScript(args []string) {
+	if len(args) < 1 {
+		t.detailView.SetText("Usage: addscript <name> [interpreter] [arg1] [arg2] ...")
+		return
+	}
+
+	name := args[0]
+	interpreter := "sh"
+	if len(args) > 1 {
+		interpreter = args[1]
+	}
+	var scriptArgs []string
+	if len(args) > 2 {
+		scriptArgs = args[2:]
+	}
+
+	t.openScriptEditor(name, interpreter, scriptArgs)
+}
+
+// openScriptEditor affiche un éditeur multi-ligne pour saisir le corps d'un job script
+func (t *TUI) openScriptEditor(name, interpreter string, args []string) {
+	editor := tview.NewTextArea().SetPlaceholder("Type the script body...")
+	editor.SetTitle(fmt.Sprintf("Script %s (%s) - Ctrl-S: submit, Esc: cancel", name, interpreter)).SetBorder(true)
+
+	editor.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyCtrlS:
+			script := editor.GetText()
+			t.closeScriptEditor()
+			t.submitScriptJob(name, interpreter, args, script)
+			return nil
+		case tcell.KeyEscape:
+			t.closeScriptEditor()
+			t.detailView.SetText("Script edition cancelled")
+			return nil
+		}
+		return event
+	})
+
+	// Centrage de l'éditeur au-dessus de l'interface principale
+	modal := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(editor, 0, 3, true).
+			AddItem(nil, 0, 1, false), 0, 3, true).
+		AddItem(nil, 0, 1, false)
+
+	t.pages.AddPage(scriptEditorPage, modal, true, true)
+	t.app.SetFocus(editor)
+}
+
+func (t *TUI) closeScriptEditor() {
+	t.pages.RemovePage(scriptEditorPage)
+	t.app.SetFocus(t.inputField)
+}
+
+func (t *TUI) submitScriptJob(name, interpreter string, args []string, script string) {
+	if strings.TrimSpace(script) == "" {
+		t.detailView.SetText("Error adding script job: the script is empty")
+		return
+	}
+
+	job := t.jobManager.PrepareJob(name, "", args, "")
+	job.Script = script
+	job.Interpreter = interpreter
+
+	if err := t.jobManager.SubmitJob(job); err != nil {
+		logger.Error(fmt.Sprintf("Error adding script job: %v", err))
+		t.detailView.SetText(fmt.Sprintf("Error adding script job: %v", err))
+		return
+	}
+
+	logger.Info(fmt.Sprintf("Script job added: %s (ID: %s)", name, job.ID))
+	t.detailView.SetText(fmt.Sprintf("Script job added successfully: %s (ID: %s)", name, job.ID))
+	t.updateJobList()
+}
+
+func (t *TUI) handleCancelJob(args []string) {
+	if len(args) != 1 {
+		t.detailView.SetText("Usage: canceljob <job_id>")
+		return
+	}
+
+	jobID := args[0]
+	if err := t.jobManager.CancelJob(jobID); err != nil {
+		logger.Error(fmt.Sprintf("Error cancelling job %s: %v", jobID, err))
+		t.detailView.SetText(fmt.Sprintf("Error cancelling job %s: %v", jobID, err))
+		return
+	}
+
+	logger.Info(fmt.Sprintf("Job cancelled: %s", jobID))
+	t.detailView.SetText(fmt.Sprintf("Job cancelled successfully: %s", jobID))
+	t.updateJobList()
+}
+
+func (t *TUI) handleRerunJob(args []string) {
+	if len(args) != 1 {
+		t.detailView.SetText("Usage: rerun <job_id>")
+		return
+	}
+
+	newJob, err := t.jobManager.RerunJob(args[0])
+	if err != nil {
+		logger.Error(fmt.Sprintf("Error rerunning job %s: %v", args[0], err))
+		t.detailView.SetText(fmt.Sprintf("Error rerunning job %s: %v", args[0], err))
+		return
+	}
+
+	t.detailView.SetText(fmt.Sprintf("Job %s rerun as %s", args[0], newJob.ID))
+	t.updateJobList()
+}
+
+const cloneUsage = "Usage: clone [-e KEY=VALUE]... [-d <working_dir>] [-i <stdin>] [-p <priority>] <job_id> [<command> <arg1> <arg2> ...]"
+
+// handleCloneJob copie un job en remplaçant les champs donnés en option, et sa commande si
+// elle est précisée après l'identifiant du job
+func (t *TUI) handleCloneJob(args []string) {
+	var options [][2]string
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		if len(args) < 2 {
+			t.detailView.SetText(cloneUsage)
+			return
+		}
+		options = append(options, [2]string{args[0], args[1]})
+		args = args[2:]
+	}
+	if len(args) < 1 {
+		t.detailView.SetText(cloneUsage)
+		return
+	}
+
+	newJob, err := t.jobManager.CopyJob(args[0])
+	if err != nil {
+		t.detailView.SetText(fmt.Sprintf("Error cloning job %s: %v", args[0], err))
+		return
+	}
+	for _, option := range options {
+		switch option[0] {
+		case "-e":
+			kv := strings.SplitN(option[1], "=", 2)
+			if len(kv) != 2 {
+				t.detailView.SetText(cloneUsage)
+				return
+			}
+			if newJob.Env == nil {
+				newJob.Env = make(map[string]string)
+			}
+			newJob.Env[kv[0]] = kv[1]
+		case "-d":
+			newJob.WorkingDir = option[1]
+		case "-i":
+			newJob.Stdin = option[1]
+		case "-p":
+			p, err := job.ParsePriority(option[1])
+			if err != nil {
+				t.detailView.SetText(fmt.Sprintf("Error cloning job %s: %v", args[0], err))
+				return
+			}
+			newJob.Priority = p
+		default:
+			t.detailView.SetText(cloneUsage)
+			return
+		}
+	}
+	if len(args) > 1 {
+		newJob.Command = args[1]
+		newJob.Args = args[2:]
+	}
+
+	if err := t.jobManager.SubmitJob(newJob); err != nil {
+		logger.Error(fmt.Sprintf("Error cloning job %s: %v", args[0], err))
+		t.detailView.SetText(fmt.Sprintf("Error cloning job %s: %v", args[0], err))
+		return
+	}
+
+	t.detailView.SetText(fmt.Sprintf("Job %s cloned as %s", args[0], newJob.ID))
+	t.updateJobList()
+}
+
+func (t *TUI) handleSetWorkers(args []string) {
+	if len(args) != 2 {
+		t.detailView.SetText("Usage: setworkers <jobs|pipelines> <count>")
+		return
+	}
+
+	count, err := strconv.Atoi(args[1])
+	if err != nil {
+		t.detailView.SetText(fmt.Sprintf("Invalid worker count: %s", args[1]))
+		return
+	}
+
+	switch args[0] {
+	case "jobs":
+		err = t.jobManager.ResizeWorkers(count)
+	case "pipelines":
+		err = t.pipelineManager.ResizeWorkers(count)
+	default:
+		t.detailView.SetText("Usage: setworkers <jobs|pipelines> <count>")
+		return
+	}
+	if err != nil {
+		logger.Error(fmt.Sprintf("Error resizing %s workers: %v", args[0], err))
+		t.detailView.SetText(fmt.Sprintf("Error resizing %s workers: %v", args[0], err))
+		return
+	}
+
+	t.detailView.SetText(fmt.Sprintf("%s worker pool resized to %d", args[0], count))
+	t.updateStats()
+}
+
+// parseLockOption lit un verrou de la forme nom ou nom:limite
+func parseLockOption(value string) (models.ResourceLock, error) {
+	parts := strings.SplitN(value, ":", 2)
+	l := models.ResourceLock{Name: parts[0], Limit: 1}
+	if len(parts) == 2 {
+		limit, err := strconv.Atoi(parts[1])
+		if err != nil || limit < 1 {
+			return l, fmt.Errorf("invalid lock limit: %s", parts[1])
+		}
+		l.Limit = limit
+	}
+	return l, nil
+}
+
+func lockLimit(l models.ResourceLock) int {
+	if l.Limit <= 0 {
+		return 1
+	}
+	return l.Limit
+}
+
+// showLocks affiche les détenteurs et les demandeurs de chaque verrou nommé
+func (t *TUI) showLocks() {
+	statuses := t.jobManager.Locks().Snapshot()
+	if len(statuses) == 0 {
+		t.detailView.SetText("No lock is held or awaited")
+		return
+	}
+
+	text := "Locks:"
+	for _, st := range statuses {
+		text += fmt.Sprintf("\n%s (%d/%d held)", st.Name, len(st.Holders), st.Limit)
+		for _, h := range st.Holders {
+			text += fmt.Sprintf("\n  held by %s %s since %s", h.Owner.Kind, h.Owner.ID, h.Since.Format("15:04:05"))
+		}
+		for _, w := range st.Waiters {
+			text += fmt.Sprintf("\n  awaited by %s %s for %s", w.Owner.Kind, w.Owner.ID, utils.FormatDuration(time.Since(w.Since)))
+		}
+	}
+	t.detailView.SetText(text)
+}
+
+// showTemplates liste les modèles de job et leurs paramètres
+func (t *TUI) showTemplates() {
+	templates, err := t.jobManager.GetTemplates()
+	if err != nil {
+		t.detailView.SetText(fmt.Sprintf("Error listing templates: %v", err))
+		return
+	}
+	if len(templates) == 0 {
+		t.detailView.SetText("No job template defined")
+		return
+	}
+
+	text := "Job templates:"
+	for _, tmpl := range templates {
+		text += fmt.Sprintf("\n%s: %s %s", tmpl.Name, tmpl.Command+tmpl.PluginName, strings.Join(tmpl.Args, " "))
+		for _, p := range tmpl.Params {
+			text += fmt.Sprintf("\n  %s (%s)", p.Name, p.Type)
+			if p.Required {
+				text += " required"
+			} else if p.Default != "" {
+				text += fmt.Sprintf(" default=%s", p.Default)
+			}
+		}
+	}
+	t.detailView.SetText(text)
+}
+
+func (t *TUI) handleRunTemplate(args []string) {
+	if len(args) < 1 {
+		t.detailView.SetText("Usage: runtemplate <name> [param=value]...")
+		return
+	}
+
+	params := make(map[string]string)
+	for _, arg := range args[1:] {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 {
+			t.detailView.SetText("Usage: runtemplate <name> [param=value]...")
+			return
+		}
+		params[kv[0]] = kv[1]
+	}
+
+	newJob, err := t.jobManager.RunTemplate(args[0], params)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Error running template %s: %v", args[0], err))
+		t.detailView.SetText(fmt.Sprintf("Error running template %s: %v", args[0], err))
+		return
+	}
+
+	logger.Info(fmt.Sprintf("Job added from template %s (ID: %s)", args[0], newJob.ID))
+	t.detailView.SetText(fmt.Sprintf("Job added from template %s (ID: %s): %s %s", args[0], newJob.ID,
+		newJob.Command, strings.Join(newJob.Args, " ")))
+	t.updateJobList()
+}
+
 func (t *TUI) handleAddPipeline(args []string) {
 	if len(args) < 2 {
 		logger.Info("Usage: addpipeline <id> <name> <job1> <job2> ...")
@@ -254,6 +753,7 @@ func (t *TUI) handleAddPipeline(args []string) {
 		Name:        name,
 		Jobs:        jobs,
 		Status:      models.PipelineStatusPending,
+		Context:     make(map[string]interface{}),
 		ScheduledAt: time.Now().Add(1 * time.Minute),
 	}
 	err := t.pipelineManager.AddPipeline(newPipeline)
@@ -285,13 +785,54 @@ func (t *TUI) handleExecutePlugin(args []string) {
 	}
 }
 
+// splitCommandLine découpe une ligne de commande en respectant les guillemets simples et doubles
+func splitCommandLine(line string) []string {
+	var parts []string
+	var current strings.Builder
+	var quote rune
+	inToken := false
+
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inToken = true
+		case r == ' ' || r == '\t':
+			if inToken {
+				parts = append(parts, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			current.WriteRune(r)
+			inToken = true
+		}
+	}
+	if inToken {
+		parts = append(parts, current.String())
+	}
+	return parts
+}
+
 func (t *TUI) showHelp() {
 	helpText := `Available commands:
     help - Display this help message
-    addjob <name> <command> <arg1> <arg2> ... - Add a new job
+    addjob [-e KEY=VALUE]... [-d <dir>] [-i <stdin>] [-p <priority>] [-l <lock>[:<limit>]]... [-a <job_id>]... [-u <user>[:<group>]] [-r <host>] <name> <command> <arg1> <arg2> ... - Add a new job
+    addscript <name> [interpreter] [arg1] ... - Add a script job written in a multi-line editor
+    canceljob <job_id> - Cancel a pending or running job
+    rerun <job_id> - Add a new run of a job with its original definition
+    clone [-e KEY=VALUE]... [-d <dir>] [-i <stdin>] [-p <priority>] <job_id> [<command> <arg1> ...] - Add a copy of a job with some fields replaced
     addpipeline <id> <name> <job1> <job2> ... - Add a new pipeline
     executeplugin <plugin_name> <arg1> <arg2> ... - Execute a plugin
-    setloglevel <DEBUG|INFO|WARNING|ERROR> - Set the log level`
+    setloglevel <DEBUG|INFO|WARNING|ERROR> - Set the log level
+    setworkers <jobs|pipelines> <count> - Resize a worker pool without interrupting running work
+    locks - Show who holds and who waits for each named lock
+    templates - List the job templates
+    runtemplate <name> [param=value]... - Add a job from a template`
 
 	t.detailView.SetText(helpText)
 }
@@ -334,18 +875,28 @@ func (t *TUI) updateLogs() {
 func (t *TUI) updateStats() {
 	var m runtime.MemStats
 	runtime.ReadMemStats(&m)
+	queueStats := t.jobManager.QueueStats()
+	jobWorkers := t.jobManager.WorkerStats()
+	pipelineWorkers := t.pipelineManager.WorkerStats()
 
 	stats := fmt.Sprintf(
 		"CPU Usage: %.2f%%\n"+
 			"Memory Usage: %v MB\n"+
 			"Goroutines: %d\n"+
 			"Jobs Running: %d\n"+
-			"Pipelines Running: %d",
+			"Jobs Queued: %d (oldest wait %s)\n"+
+			"Pipelines Running: %d\n"+
+			"Job Workers: %d/%d busy\n"+
+			"Pipeline Workers: %d/%d busy",
 		t.getCPUUsage(),
 		m.Alloc/1024/1024,
 		runtime.NumGoroutine(),
 		t.getRunningJobsCount(),
+		queueStats.Depth,
+		utils.FormatDuration(queueStats.OldestWait),
 		t.getRunningPipelinesCount(),
+		jobWorkers.Busy, jobWorkers.Size,
+		pipelineWorkers.Busy, pipelineWorkers.Size,
 	)
 
 	t.statsView.Clear()
@@ -0,0 +1,103 @@
+package workerpool
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Stats décrit l'utilisation d'un pool de workers
+type Stats struct {
+	Size int
+	Busy int
+}
+
+// Pool suit la taille cible d'un ensemble de workers et permet de la modifier à chaud.
+// Les workers en surnombre s'arrêtent d'eux-mêmes avant de prendre un nouveau travail,
+// si bien qu'aucun travail en cours n'est interrompu
+type Pool struct {
+	mu       sync.Mutex
+	size     int
+	retiring int
+	busy     int
+	wake     chan struct{}
+	start    func()
+}
+
+// New crée un pool vide dont les workers exécutent la fonction start. Les workers
+// sont lancés par Resize, une fois que le pool est accessible depuis start
+func New(start func()) *Pool {
+	return &Pool{
+		wake:  make(chan struct{}),
+		start: start,
+	}
+}
+
+// Resize fixe le nombre de workers. En réduction, les workers occupés terminent leur travail
+func (p *Pool) Resize(size int) error {
+	if size < 1 {
+		return fmt.Errorf("worker count must be at least 1, got %d", size)
+	}
+
+	p.mu.Lock()
+	delta := size - p.size
+	p.size = size
+
+	// Un agrandissement annule d'abord les départs encore en attente
+	for delta > 0 && p.retiring > 0 {
+		p.retiring--
+		delta--
+	}
+	if delta < 0 {
+		p.retiring -= delta
+	}
+
+	// Réveille les workers inactifs pour qu'ils constatent la nouvelle taille
+	close(p.wake)
+	p.wake = make(chan struct{})
+	p.mu.Unlock()
+
+	for ; delta > 0; delta-- {
+		go p.start()
+	}
+	return nil
+}
+
+// Retire est appelé par un worker avant de prendre un travail. Il indique si le worker
+// doit s'arrêter pour ramener le pool à sa taille cible
+func (p *Pool) Retire() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.retiring == 0 {
+		return false
+	}
+	p.retiring--
+	return true
+}
+
+// Wake retourne un canal fermé lors du prochain redimensionnement
+func (p *Pool) Wake() <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.wake
+}
+
+// Begin et Done encadrent l'exécution d'un travail par un worker
+func (p *Pool) Begin() {
+	p.mu.Lock()
+	p.busy++
+	p.mu.Unlock()
+}
+
+func (p *Pool) Done() {
+	p.mu.Lock()
+	p.busy--
+	p.mu.Unlock()
+}
+
+// Stats retourne la taille cible du pool et le nombre de workers occupés
+func (p *Pool) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return Stats{Size: p.size, Busy: p.busy}
+}